@@ -2,7 +2,6 @@ package ennet
 
 import (
 	"errors"
-	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,6 +16,10 @@ const (
 	Element
 	Text
 	Group
+
+	Comment  // <!--Data-->
+	CData    // <![CDATA[Data]]>
+	ProcInst // <?Data?>, the target and the instruction
 )
 
 var nodeType2String = map[NodeType]string{
@@ -25,6 +28,10 @@ var nodeType2String = map[NodeType]string{
 	Element: "element",
 	Text:    "text",
 	Group:   "group",
+
+	Comment:  "comment",
+	CData:    "cdata",
+	ProcInst: "procinst",
 }
 
 func (t NodeType) String() string {
@@ -45,6 +52,12 @@ type Node struct {
 	Attributes []Attribute
 
 	Mul int
+	// MulData is the name of the data to multiply over (*@items), instead of Mul.
+	MulData string
+
+	// Pos and End locate the node in the abbreviation, like Token.Pos.
+	// They are 0 if the builder was not told.
+	Pos, End int
 
 	Parent, FirstChild, LastChild, NextSibling, PrevSibling *Node
 }
@@ -58,6 +71,38 @@ func (n *Node) GetAttribute(name string) string {
 	return ""
 }
 
+// Prefix returns the namespace prefix of the name (soap of soap:Envelope), or "".
+func (n *Node) Prefix() string {
+	prefix, _ := splitQName(n.Data)
+	return prefix
+}
+
+// LocalName returns the name without the prefix (Envelope of soap:Envelope).
+func (n *Node) LocalName() string {
+	_, local := splitQName(n.Data)
+	return local
+}
+
+// NamespaceURI returns the namespace URI of the prefix of n,
+// declared by the xmlns attributes of n or its ancestors (including NodeBuilder.DeclareNamespace),
+// or "" if not declared.
+func (n *Node) NamespaceURI() string {
+	name := "xmlns"
+	if prefix := n.Prefix(); prefix == "xml" {
+		return "http://www.w3.org/XML/1998/namespace"
+	} else if prefix != "" {
+		name += ":" + prefix
+	}
+	for a := n; a != nil; a = a.Parent {
+		for _, attr := range a.Attributes {
+			if attr.Name == name {
+				return attr.Value
+			}
+		}
+	}
+	return ""
+}
+
 func (n *Node) AppendChild(child *Node) *Node {
 	if lc := n.LastChild; lc != nil {
 		lc.NextSibling = child
@@ -83,22 +128,20 @@ func (n *Node) dump(indent int) string {
 		s.WriteString(n.Data + ":" + n.Type.String())
 	} else if n.Type == Text {
 		s.WriteString(`"` + n.Data + `"`)
+	} else if n.Type == Comment || n.Type == CData || n.Type == ProcInst {
+		s.WriteString(n.Type.String() + `:"` + n.Data + `"`)
 	}
 
-	if len(n.Attributes) > 0 {
-		attrs := make([]Attribute, len(n.Attributes))
-		copy(attrs, n.Attributes)
-		slices.SortFunc(attrs, func(a, b Attribute) int {
-			return strings.Compare(a.Name, b.Name)
-		})
-		for _, attr := range attrs {
-			s.WriteString(" @" + attr.Name + "=" + attr.Value)
-		}
+	for _, attr := range n.Attributes {
+		s.WriteString(" @" + attr.Name + "=" + attr.Value)
 	}
 
 	if n.Mul > 1 {
 		s.WriteString(" *" + strconv.Itoa(n.Mul))
 	}
+	if n.MulData != "" {
+		s.WriteString(" *@" + n.MulData)
+	}
 
 	child := n.FirstChild
 	for child != nil {
@@ -124,10 +167,46 @@ type Builder interface {
 	GroupEnd() error
 }
 
+// DataBuilder is a Builder that accepts multiplications over data (*@items).
+// Parse fails on *@ if the builder is not a DataBuilder.
+type DataBuilder interface {
+	Builder
+	MulData(name string) error
+}
+
+// MarkupBuilder is a Builder that accepts comments (!--{text}), CDATA sections (!cdata{text})
+// and processing instructions (?target{text}).
+// Parse fails on them if the builder is not a MarkupBuilder.
+type MarkupBuilder interface {
+	Builder
+	Comment(text string) error
+	CData(text string) error
+	ProcInst(target, text string) error
+}
+
+// PosBuilder is a Builder that is told where the next call comes from.
+// Parse calls Pos before the other methods of the builder.
+type PosBuilder interface {
+	Builder
+	Pos(pos, end int)
+}
+
 type NodeBuilder struct {
 	Root *Node
 	curr *Node
 
+	// Merges are MergeFuncs of repeated attributes by name, "" for the others.
+	// Without them, class is merged by MergeTokens and the others by MergeLast.
+	Merges map[string]MergeFunc
+
+	// Defaults are attributes of elements by name, overridden by explicit ones.
+	Defaults map[string][]Attribute
+	// default attributes of defaultsOf, by bit of the index
+	defaultsOf *Node
+	defaults   uint64
+
+	pos, end int
+
 	pool *sync.Pool
 }
 
@@ -158,11 +237,29 @@ func NewNodeBuilder(pool *sync.Pool) NodeBuilder {
 	return b
 }
 
+func (nb *NodeBuilder) Pos(pos, end int) {
+	nb.pos = pos
+	nb.end = end
+}
+
+// span extends the range of n by the last Pos.
+func (nb *NodeBuilder) span(n *Node) {
+	if nb.pos == 0 {
+		return
+	}
+	if n.Pos == 0 || nb.pos < n.Pos {
+		n.Pos = nb.pos
+	}
+	n.End = max(n.End, nb.end)
+}
+
 func (nb *NodeBuilder) Element(name string) error {
 	if nb.curr.Type == WIP {
 		nb.curr.Type = Element
 		nb.curr.Data = name
+		nb.addDefaults(name)
 	}
+	nb.span(nb.curr)
 
 	return nil
 }
@@ -178,10 +275,18 @@ func (nb *NodeBuilder) Attribute(name, value string) error {
 	if nb.curr.Type == WIP {
 		nb.curr.Type = Element
 	}
+	nb.span(nb.curr)
 
 	for i := range nb.curr.Attributes {
-		if nb.curr.Attributes[i].Name == name {
-			nb.curr.Attributes[i].Value += " " + value
+		if attr := &nb.curr.Attributes[i]; attr.Name == name {
+			if nb.overrideDefault(i, value) {
+				return nil
+			}
+			merged, err := nb.merge(name)(name, attr.Value, value)
+			if err != nil {
+				return err
+			}
+			attr.Value = merged
 			return nil
 		}
 	}
@@ -200,6 +305,14 @@ func (nb *NodeBuilder) Class(name string) error {
 
 func (nb *NodeBuilder) Mul(count int) error {
 	nb.curr.Mul = count
+	nb.span(nb.curr)
+
+	return nil
+}
+
+func (nb *NodeBuilder) MulData(name string) error {
+	nb.curr.MulData = name
+	nb.span(nb.curr)
 
 	return nil
 }
@@ -207,18 +320,22 @@ func (nb *NodeBuilder) Mul(count int) error {
 func (nb *NodeBuilder) Text(text string) error {
 	if nb.curr.Type == Text {
 		nb.curr.Data += text
+		nb.span(nb.curr)
 		return nil
 	}
 
 	if nb.curr.Type == WIP {
 		nb.curr.Type = Text
 		nb.curr.Data += text
+		nb.span(nb.curr)
 		return nil
 	}
 
 	node := nb.NewNode()
 	node.Type = Text
 	node.Data = text
+	nb.span(node)
+	nb.span(nb.curr)
 	nb.curr.AppendChild(node)
 
 	return nil
@@ -227,6 +344,7 @@ func (nb *NodeBuilder) Text(text string) error {
 func (nb *NodeBuilder) GroupBegin() error {
 	if nb.curr.Type == WIP {
 		nb.curr.Type = Group
+		nb.span(nb.curr)
 		node := nb.NewNode()
 		node.Type = WIP
 		nb.curr.AppendChild(node)
@@ -234,6 +352,7 @@ func (nb *NodeBuilder) GroupBegin() error {
 	} else {
 		node := nb.NewNode()
 		node.Type = Group
+		nb.span(node)
 		nb.curr.AppendChild(node)
 		nb.curr = node
 	}
@@ -254,6 +373,42 @@ func (nb *NodeBuilder) GroupEnd() error {
 		}
 		nb.curr = nb.curr.Parent
 	}
+	if nb.curr.Type == Group {
+		nb.span(nb.curr)
+	}
+
+	return nil
+}
+
+func (nb *NodeBuilder) Comment(text string) error {
+	return nb.markup(Comment, text)
+}
+
+func (nb *NodeBuilder) CData(text string) error {
+	return nb.markup(CData, text)
+}
+
+func (nb *NodeBuilder) ProcInst(target, text string) error {
+	if text != "" {
+		target += " " + text
+	}
+	return nb.markup(ProcInst, target)
+}
+
+func (nb *NodeBuilder) markup(typ NodeType, data string) error {
+	if nb.curr.Type == WIP {
+		nb.curr.Type = typ
+		nb.curr.Data = data
+		nb.span(nb.curr)
+		return nil
+	}
+
+	node := nb.NewNode()
+	node.Type = typ
+	node.Data = data
+	nb.span(node)
+	nb.span(nb.curr)
+	nb.curr.AppendChild(node)
 
 	return nil
 }
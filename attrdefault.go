@@ -0,0 +1,89 @@
+package ennet
+
+import "slices"
+
+// htmlDefaultAttributes are attributes of HTML elements without explicit ones, as in Emmet
+// (a to <a href="">). It is never modified.
+var htmlDefaultAttributes = map[string][]Attribute{
+	"a":        {{Name: "href"}},
+	"area":     {{Name: "shape"}, {Name: "coords"}, {Name: "href"}, {Name: "alt"}},
+	"base":     {{Name: "href"}},
+	"bdo":      {{Name: "dir"}},
+	"embed":    {{Name: "src"}, {Name: "type"}},
+	"form":     {{Name: "action"}},
+	"iframe":   {{Name: "src"}, {Name: "frameborder", Value: "0"}},
+	"img":      {{Name: "src"}, {Name: "alt"}},
+	"input":    {{Name: "type"}},
+	"label":    {{Name: "for"}},
+	"link":     {{Name: "rel", Value: "stylesheet"}, {Name: "href"}},
+	"map":      {{Name: "name"}},
+	"object":   {{Name: "data"}, {Name: "type"}},
+	"option":   {{Name: "value"}},
+	"param":    {{Name: "name"}, {Name: "value"}},
+	"select":   {{Name: "name"}, {Name: "id"}},
+	"source":   {{Name: "src"}, {Name: "type"}},
+	"textarea": {{Name: "name"}, {Name: "id"}, {Name: "cols", Value: "30"}, {Name: "rows", Value: "10"}},
+}
+
+// HTMLDefaultAttributes returns a copy of the default attributes of HTML elements, as in Emmet
+// (a to <a href="">, img to <img src="" alt="">).
+func HTMLDefaultAttributes() map[string][]Attribute {
+	return cloneDefaults(htmlDefaultAttributes)
+}
+
+// WithDefaultAttributes sets the attributes of elements by name, overridden by explicit ones.
+// defaults is copied, and nil turns them off.
+//
+// The default is HTMLDefaultAttributes in HTML and XHTML modes, and none in XML mode.
+func WithDefaultAttributes(defaults map[string][]Attribute) Option {
+	defaults = cloneDefaults(defaults)
+	return func(o *options) {
+		o.defaultAttrs = defaults
+		o.defaultAttrsSet = true
+	}
+}
+
+func cloneDefaults(defaults map[string][]Attribute) map[string][]Attribute {
+	if defaults == nil {
+		return nil
+	}
+	clone := make(map[string][]Attribute, len(defaults))
+	for name, attrs := range defaults {
+		clone[name] = slices.Clone(attrs)
+	}
+	return clone
+}
+
+func (o *options) defaultAttributes() map[string][]Attribute {
+	if o.defaultAttrsSet || o.mode == XML {
+		return o.defaultAttrs
+	}
+	return htmlDefaultAttributes
+}
+
+// addDefaults adds the default attributes of the element name to the current node.
+func (nb *NodeBuilder) addDefaults(name string) {
+	defaults := nb.Defaults[name]
+	if len(defaults) == 0 {
+		return
+	}
+
+	nb.defaultsOf = nb.curr
+	nb.defaults = 0
+	for _, attr := range defaults {
+		if i := len(nb.curr.Attributes); i < 64 {
+			nb.defaults |= 1 << i
+		}
+		nb.curr.Attributes = append(nb.curr.Attributes, attr)
+	}
+}
+
+// overrideDefault replaces the i-th attribute if it is a default one.
+func (nb *NodeBuilder) overrideDefault(i int, value string) bool {
+	if nb.defaultsOf != nb.curr || i >= 64 || nb.defaults&(1<<i) == 0 {
+		return false
+	}
+	nb.curr.Attributes[i].Value = value
+	nb.defaults &^= 1 << i
+	return true
+}
@@ -0,0 +1,60 @@
+package ennet
+
+import (
+	"slices"
+	"strings"
+)
+
+// AttrOrder compares attributes of an element to order them in the output,
+// like the cmp of slices.SortStableFunc.
+// Attributes that compare equal stay in the source order.
+type AttrOrder func(a, b Attribute) int
+
+var (
+	// SourceOrder writes id, class, then the other attributes in the source order, as in Emmet.
+	// It is the default.
+	SourceOrder = AttrPriority("id", "class")
+
+	// AlphabeticalOrder writes attributes sorted by name.
+	AlphabeticalOrder AttrOrder = func(a, b Attribute) int {
+		return strings.Compare(a.Name, b.Name)
+	}
+)
+
+// AttrPriority writes the attributes names first in that order,
+// then the others in the source order.
+func AttrPriority(names ...string) AttrOrder {
+	names = slices.Clone(names)
+	rank := func(name string) int {
+		if i := slices.Index(names, name); i != -1 {
+			return i
+		}
+		return len(names)
+	}
+	return func(a, b Attribute) int {
+		return rank(a.Name) - rank(b.Name)
+	}
+}
+
+// WithAttrOrder sets the order of attributes in the output. The default is SourceOrder.
+func WithAttrOrder(order AttrOrder) Option {
+	return func(o *options) {
+		o.attrOrder = order
+	}
+}
+
+// orderedAttributes returns the attributes of n in the output order,
+// without changing n.
+func (r *renderer) orderedAttributes(n *Node) []Attribute {
+	order := r.opts.attrOrder
+	if order == nil {
+		order = SourceOrder
+	}
+	if slices.IsSortedFunc(n.Attributes, order) {
+		return n.Attributes
+	}
+
+	r.attrs = append(r.attrs[:0], n.Attributes...)
+	slices.SortStableFunc(r.attrs, order)
+	return r.attrs
+}
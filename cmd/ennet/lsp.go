@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/shu-go/ennet"
+)
+
+// lspServer is a Language Server Protocol server over a stream.
+//
+// It offers
+//   - completion items that expand the abbreviation before the cursor,
+//   - hover previews of the abbreviation under the cursor, and
+//   - diagnostics for documents of language "ennet", one abbreviation per line.
+type lspServer struct {
+	r    *bufio.Reader
+	w    io.Writer
+	opts []ennet.Option
+
+	docs map[string]*lspDocument
+}
+
+type lspDocument struct {
+	languageID string
+	text       string
+}
+
+// Limits of an expansion, since completion and hover expand any word of the documents.
+const (
+	lspMaxMul    = 1000
+	lspMaxOutput = 1024 * 1024
+)
+
+func serveLSP(r io.Reader, w io.Writer, opts []ennet.Option) error {
+	s := lspServer{
+		r:    bufio.NewReader(r),
+		w:    w,
+		opts: append(slices.Clip(opts), ennet.WithMaxMul(lspMaxMul), ennet.WithMaxOutput(lspMaxOutput)),
+		docs: make(map[string]*lspDocument),
+	}
+	return s.serve()
+}
+
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextDocumentPosition struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+func (s *lspServer) serve() error {
+	for {
+		msg, err := s.read()
+		var rerr *rpcError
+		if errors.As(err, &rerr) {
+			// a message that is not a request, answered without an id
+			null := json.RawMessage("null")
+			if err := s.write(rpcMessage{JSONRPC: "2.0", ID: &null, Error: rerr}); err != nil {
+				return err
+			}
+			continue
+		} else if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		result, rerr := s.handle(msg)
+		if msg.Method == "exit" {
+			return nil
+		}
+		if msg.ID == nil {
+			continue
+		}
+
+		resp := rpcMessage{JSONRPC: "2.0", ID: msg.ID, Error: rerr}
+		if rerr == nil {
+			resp.Result = json.RawMessage("null")
+			if result != nil {
+				resp.Result = result
+			}
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *lspServer) handle(msg *rpcMessage) (any, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   1, // full
+				"completionProvider": map[string]any{},
+				"hoverProvider":      true,
+			},
+			"serverInfo": map[string]any{"name": "ennet"},
+		}, nil
+
+	case "shutdown", "initialized", "exit":
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI        string `json:"uri"`
+				LanguageID string `json:"languageId"`
+				Text       string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: -32602, Message: err.Error()}
+		}
+		td := params.TextDocument
+		s.docs[td.URI] = &lspDocument{languageID: td.LanguageID, text: td.Text}
+		return nil, s.publishDiagnostics(td.URI)
+
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: -32602, Message: err.Error()}
+		}
+		doc := s.docs[params.TextDocument.URI]
+		if doc == nil || len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		doc.text = params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+
+	case "textDocument/didClose":
+		var params lspTextDocumentPosition
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: -32602, Message: err.Error()}
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, nil
+
+	case "textDocument/completion":
+		var params lspTextDocumentPosition
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: -32602, Message: err.Error()}
+		}
+		return s.completion(params), nil
+
+	case "textDocument/hover":
+		var params lspTextDocumentPosition
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: -32602, Message: err.Error()}
+		}
+		return s.hover(params), nil
+
+	default:
+		if msg.ID == nil {
+			return nil, nil // ignore unknown notifications
+		}
+		return nil, &rpcError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+}
+
+// completion offers the expansion of the abbreviation before the cursor.
+func (s *lspServer) completion(params lspTextDocumentPosition) any {
+	items := []any{}
+
+	line, col, ok := s.lineAt(params.TextDocument.URI, params.Position)
+	if !ok {
+		return items
+	}
+	start, _ := abbreviationBounds(line, col)
+	abbr := line[start:col]
+	if abbr == "" {
+		return items
+	}
+	expanded, err := ennet.Expand(abbr, s.opts...)
+	if err != nil {
+		return items
+	}
+
+	items = append(items, map[string]any{
+		"label":  abbr,
+		"kind":   15, // snippet
+		"detail": expanded,
+		"textEdit": map[string]any{
+			"range": lspRange{
+				Start: lspPosition{Line: params.Position.Line, Character: utf16Len(line[:start])},
+				End:   params.Position,
+			},
+			"newText": expanded,
+		},
+	})
+	return items
+}
+
+// hover previews the expansion of the abbreviation under the cursor.
+func (s *lspServer) hover(params lspTextDocumentPosition) any {
+	line, col, ok := s.lineAt(params.TextDocument.URI, params.Position)
+	if !ok {
+		return nil
+	}
+	start, end := abbreviationBounds(line, col)
+	if start == end {
+		return nil
+	}
+
+	expanded, err := ennet.Expand(line[start:end], s.opts...)
+	if err != nil {
+		return nil
+	}
+	return map[string]any{
+		"contents": map[string]any{
+			"kind":  "markdown",
+			"value": "```html\n" + expanded + "\n```",
+		},
+		"range": lspRange{
+			Start: lspPosition{Line: params.Position.Line, Character: utf16Len(line[:start])},
+			End:   lspPosition{Line: params.Position.Line, Character: utf16Len(line[:end])},
+		},
+	}
+}
+
+func (s *lspServer) publishDiagnostics(uri string) *rpcError {
+	doc := s.docs[uri]
+	if doc == nil || doc.languageID != "ennet" {
+		return nil
+	}
+
+	diags := []any{}
+	for i, line := range strings.Split(doc.text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		nb := ennet.NewNodeBuilder(nil)
+		for _, perr := range ennet.ParseTolerant([]byte(line), &nb) {
+			diags = append(diags, map[string]any{
+				"range": lspRange{
+					Start: lspPosition{Line: i, Character: utf16Len(line[:min(perr.Pos-1, len(line))])},
+					End:   lspPosition{Line: i, Character: utf16Len(line[:min(perr.End-1, len(line))])},
+				},
+				"severity": 1, // error
+				"source":   "ennet",
+				"message":  perr.Err.Error(),
+			})
+		}
+	}
+
+	params, _ := json.Marshal(map[string]any{"uri": uri, "diagnostics": diags})
+	err := s.write(rpcMessage{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  params,
+	})
+	if err != nil {
+		return &rpcError{Code: -32603, Message: err.Error()}
+	}
+	return nil
+}
+
+// lineAt returns the line at pos and the byte offset of pos in it.
+func (s *lspServer) lineAt(uri string, pos lspPosition) (string, int, bool) {
+	doc := s.docs[uri]
+	if doc == nil {
+		return "", 0, false
+	}
+
+	lines := strings.Split(doc.text, "\n")
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return "", 0, false
+	}
+	line := strings.TrimRight(lines[pos.Line], "\r")
+
+	// UTF-16 code units to bytes
+	col, units := 0, 0
+	for col < len(line) && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(line[col:])
+		units += utf16.RuneLen(r)
+		col += size
+	}
+	return line, col, true
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// abbreviationBounds returns the range of the abbreviation at col.
+// Abbreviations are separated by spaces, except in {TEXT}, [attributes] and quotes,
+// so the line is scanned from its start.
+func abbreviationBounds(line string, col int) (start, end int) {
+	const (
+		top = iota
+		text
+		attrs
+	)
+	mode, outer := top, top
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				if i+1 < len(line) && line[i+1] == quote {
+					i++ // escaped
+					continue
+				}
+				quote, mode = 0, outer
+			}
+		case mode == text:
+			if c == '$' && i+1 < len(line) && line[i+1] == '{' {
+				if j := strings.IndexByte(line[i:], '}'); j != -1 {
+					i += j // ${placeholder}
+				}
+			} else if c == '}' {
+				if i+1 < len(line) && line[i+1] == '}' {
+					i++ // escaped
+					continue
+				}
+				mode = top
+			}
+		case c == '"' || c == '\'':
+			quote, outer = c, mode
+		case mode == attrs:
+			if c == ']' {
+				mode = top
+			}
+		case c == '{':
+			mode = text
+		case c == '[':
+			mode = attrs
+		case c == ' ' || c == '\t':
+			if col <= i {
+				return start, i
+			}
+			start = i + 1
+		}
+	}
+	return start, len(line)
+}
+
+// maxContentLength limits the size of a message, far beyond any document being edited.
+const maxContentLength = 64 << 20
+
+func (s *lspServer) read() (*rpcMessage, error) {
+	tp := textproto.NewReader(s.r)
+	header, err := tp.ReadMIMEHeader()
+	if err == io.EOF {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, errors.New("invalid Content-Length")
+	}
+	if length > maxContentLength {
+		return nil, errors.New("Content-Length exceeds " + strconv.Itoa(maxContentLength))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.r, body); err != nil {
+		return nil, err
+	}
+
+	var msg rpcMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		var serr *json.SyntaxError
+		if errors.As(err, &serr) {
+			return nil, &rpcError{Code: -32700, Message: "parse error: " + err.Error()}
+		}
+		return nil, &rpcError{Code: -32600, Message: "invalid request: " + err.Error()}
+	}
+	return &msg, nil
+}
+
+func (s *lspServer) write(msg rpcMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
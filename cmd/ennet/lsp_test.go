@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shu-go/gotwant"
+)
+
+type lspClient struct {
+	t    *testing.T
+	in   io.WriteCloser
+	out  *bufio.Reader
+	done chan int
+	id   int
+}
+
+func startLSP(t *testing.T, args ...string) *lspClient {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	c := &lspClient{t: t, in: inW, out: bufio.NewReader(outR), done: make(chan int)}
+	go func() {
+		var stderr bytes.Buffer
+		status := run(append([]string{"lsp"}, args...), inR, outW, &stderr)
+		outW.Close()
+		c.done <- status
+	}()
+	return c
+}
+
+func (c *lspClient) send(method string, params any, withID bool) int {
+	c.t.Helper()
+
+	msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if withID {
+		c.id++
+		msg["id"] = c.id
+	}
+	body, _ := json.Marshal(msg)
+	_, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	gotwant.TestError(c.t, err, nil)
+	return c.id
+}
+
+func (c *lspClient) recv() map[string]any {
+	c.t.Helper()
+
+	header, err := textproto.NewReader(c.out).ReadMIMEHeader()
+	gotwant.TestError(c.t, err, nil)
+	length, _ := strconv.Atoi(header.Get("Content-Length"))
+	body := make([]byte, length)
+	_, err = io.ReadFull(c.out, body)
+	gotwant.TestError(c.t, err, nil)
+
+	var msg map[string]any
+	gotwant.TestError(c.t, json.Unmarshal(body, &msg), nil)
+	return msg
+}
+
+func (c *lspClient) request(method string, params any) map[string]any {
+	c.t.Helper()
+
+	id := c.send(method, params, true)
+	msg := c.recv()
+	gotwant.Test(c.t, msg["id"], float64(id))
+	return msg
+}
+
+func TestLSP(t *testing.T) {
+	c := startLSP(t, "-mode", "html")
+
+	resp := c.request("initialize", map[string]any{})
+	caps := resp["result"].(map[string]any)["capabilities"].(map[string]any)
+	gotwant.Test(t, caps["hoverProvider"], true)
+	c.send("initialized", map[string]any{}, false)
+
+	t.Run("Diagnostics", func(t *testing.T) {
+		c.send("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{
+				"uri":        "file:///a.ennet",
+				"languageId": "ennet",
+				"version":    1,
+				"text":       "ul>li*3\nul>li#+a",
+			},
+		}, false)
+		msg := c.recv()
+		gotwant.Test(t, msg["method"], "textDocument/publishDiagnostics")
+		diags := msg["params"].(map[string]any)["diagnostics"].([]any)
+		gotwant.Test(t, len(diags), 1)
+		diag := diags[0].(map[string]any)
+		gotwant.Test(t, diag["message"], "id name is required")
+		gotwant.Test(t, diag["range"], map[string]any{
+			"start": map[string]any{"line": float64(1), "character": float64(6)},
+			"end":   map[string]any{"line": float64(1), "character": float64(7)},
+		})
+
+		c.send("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": "file:///a.ennet", "version": 2},
+			"contentChanges": []any{map[string]any{"text": "ul>li*3\nul>li#x+a"}},
+		}, false)
+		msg = c.recv()
+		diags = msg["params"].(map[string]any)["diagnostics"].([]any)
+		gotwant.Test(t, len(diags), 0)
+	})
+
+	t.Run("Completion", func(t *testing.T) {
+		c.send("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{
+				"uri":        "file:///b.html",
+				"languageId": "html",
+				"version":    1,
+				"text":       "<body>\n  ul>li{a b}*2 \n</body>",
+			},
+		}, false)
+
+		resp := c.request("textDocument/completion", map[string]any{
+			"textDocument": map[string]any{"uri": "file:///b.html"},
+			"position":     map[string]any{"line": 1, "character": 14},
+		})
+		items := resp["result"].([]any)
+		gotwant.Test(t, len(items), 1)
+		item := items[0].(map[string]any)
+		gotwant.Test(t, item["label"], "ul>li{a b}*2")
+		gotwant.Test(t, item["textEdit"], map[string]any{
+			"range": map[string]any{
+				"start": map[string]any{"line": float64(1), "character": float64(2)},
+				"end":   map[string]any{"line": float64(1), "character": float64(14)},
+			},
+			"newText": "<ul><li>a b</li><li>a b</li></ul>",
+		})
+	})
+
+	t.Run("Hover", func(t *testing.T) {
+		resp := c.request("textDocument/hover", map[string]any{
+			"textDocument": map[string]any{"uri": "file:///b.html"},
+			"position":     map[string]any{"line": 1, "character": 4},
+		})
+		result := resp["result"].(map[string]any)
+		gotwant.Test(t, result["contents"].(map[string]any)["value"], "```html\n<ul><li>a b</li><li>a b</li></ul>\n```")
+
+		resp = c.request("textDocument/hover", map[string]any{
+			"textDocument": map[string]any{"uri": "file:///b.html"},
+			"position":     map[string]any{"line": 0, "character": 1},
+		})
+		gotwant.Test(t, resp["result"], nil)
+	})
+
+	t.Run("Inside", func(t *testing.T) {
+		c.send("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{
+				"uri":        "file:///c.html",
+				"languageId": "html",
+				"version":    1,
+				"text":       "x a[href=x title=y] ul>li{a b}*2\na{hello wor",
+			},
+		}, false)
+
+		for _, h := range []struct {
+			character int
+			want      string
+		}{
+			{13, "```html\n<a href=\"x\" title=\"y\"></a>\n```"},    // title
+			{28, "```html\n<ul><li>a b</li><li>a b</li></ul>\n```"}, // b
+		} {
+			resp := c.request("textDocument/hover", map[string]any{
+				"textDocument": map[string]any{"uri": "file:///c.html"},
+				"position":     map[string]any{"line": 0, "character": h.character},
+			})
+			gotwant.Test(t, resp["result"].(map[string]any)["contents"].(map[string]any)["value"], h.want)
+		}
+
+		resp := c.request("textDocument/completion", map[string]any{
+			"textDocument": map[string]any{"uri": "file:///c.html"},
+			"position":     map[string]any{"line": 1, "character": 11},
+		})
+		gotwant.Test(t, len(resp["result"].([]any)), 0)
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		c.send("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{
+				"uri":        "file:///d.html",
+				"languageId": "html",
+				"version":    1,
+				"text":       "x*999999999 (a*1000)*1000 lorem999999999",
+			},
+		}, false)
+
+		for _, character := range []int{5, 15, 30} {
+			start := time.Now()
+			resp := c.request("textDocument/hover", map[string]any{
+				"textDocument": map[string]any{"uri": "file:///d.html"},
+				"position":     map[string]any{"line": 0, "character": character},
+			})
+			gotwant.Test(t, resp["result"], nil, gotwant.Desc(strconv.Itoa(character)))
+			if d := time.Since(start); d > time.Second {
+				t.Errorf("hover at %d took %v", character, d)
+			}
+		}
+
+		resp := c.request("textDocument/completion", map[string]any{
+			"textDocument": map[string]any{"uri": "file:///d.html"},
+			"position":     map[string]any{"line": 0, "character": 11},
+		})
+		gotwant.Test(t, len(resp["result"].([]any)), 0)
+	})
+
+	t.Run("ParseError", func(t *testing.T) {
+		_, err := fmt.Fprintf(c.in, "Content-Length: 1\r\n\r\n{")
+		gotwant.TestError(t, err, nil)
+		msg := c.recv()
+		gotwant.Test(t, msg["id"], nil)
+		gotwant.Test(t, msg["error"].(map[string]any)["code"], float64(-32700))
+
+		_, err = fmt.Fprintf(c.in, "Content-Length: 3\r\n\r\n[1]")
+		gotwant.TestError(t, err, nil)
+		msg = c.recv()
+		gotwant.Test(t, msg["error"].(map[string]any)["code"], float64(-32600))
+
+		resp := c.request("workspace/symbol", map[string]any{})
+		gotwant.Test(t, resp["error"].(map[string]any)["code"], float64(-32601))
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		resp := c.request("workspace/symbol", map[string]any{})
+		gotwant.Test(t, resp["error"].(map[string]any)["code"], float64(-32601))
+	})
+
+	c.request("shutdown", nil)
+	c.send("exit", nil, false)
+	gotwant.Test(t, <-c.done, 0)
+}
+
+func TestAbbreviationBounds(t *testing.T) {
+	for _, c := range []struct {
+		line       string
+		col        int
+		start, end int
+	}{
+		{"ul>li{a b}*2", 8, 0, 12},
+		{"x a[href=x title=y] z", 11, 2, 19},
+		{`p["a ]b" c]+q`, 5, 0, 13},
+		{`x {a}} b} y`, 7, 2, 9},
+		{"x {${a b}} y", 6, 2, 10},
+		{"a{hello wor", 11, 0, 11},
+		{"a b", 1, 0, 1},
+		{"a b", 2, 2, 3},
+	} {
+		start, end := abbreviationBounds(c.line, c.col)
+		gotwant.Test(t, [2]int{start, end}, [2]int{c.start, c.end}, gotwant.Desc(c.line))
+	}
+}
+
+func TestLSPContentLength(t *testing.T) {
+	for _, length := range []string{"-1", "x", strconv.Itoa(1 << 30)} {
+		err := serveLSP(strings.NewReader("Content-Length: "+length+"\r\n\r\n{}"), io.Discard, nil)
+		gotwant.TestError(t, err, "Content-Length", gotwant.Desc(length))
+	}
+}
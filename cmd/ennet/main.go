@@ -0,0 +1,216 @@
+// Command ennet expands Emmet-like abbreviations.
+//
+// Usage:
+//
+//	ennet [flags] [abbreviation ...]
+//	ennet lsp [flags]
+//	ennet doc [flags] [file ...]
+//
+// Without arguments, ennet reads abbreviations from the standard input, one per line,
+// and writes one expansion per line.
+// On errors, ennet reports them with their positions and exits with 1.
+//
+// "ennet lsp" runs a Language Server Protocol server over the standard input and output.
+//
+// "ennet doc" expands abbreviations embedded in documents (see ennet.ExpandDocument),
+// from the files or the standard input.
+// With -w, the files are rewritten instead of written to the standard output.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shu-go/ennet"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var command string
+	if len(args) > 0 && (args[0] == "lsp" || args[0] == "doc") {
+		command = args[0]
+		args = args[1:]
+	}
+
+	flags := flag.NewFlagSet("ennet", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: ennet [flags] [abbreviation ...]")
+		fmt.Fprintln(stderr, "       ennet lsp [flags]")
+		fmt.Fprintln(stderr, "       ennet doc [flags] [file ...]")
+		flags.PrintDefaults()
+	}
+	mode := flags.String("mode", "xml", "output `mode`: xml, html or xhtml")
+	indent := flags.Int("indent", 0, "indent child elements by `n` spaces")
+	tabs := flags.Bool("tabs", false, "indent child elements by tabs")
+	escape := flags.Bool("escape", false, "escape &, <, > and \" in texts and attribute values")
+	ast := flags.Bool("ast", false, "print the parsed tree instead of the expansion")
+	write := flags.Bool("w", false, "doc: rewrite the files")
+	begin := flags.String("begin", ennet.DefaultDelimiters.Begin, "doc: the `delimiter` before an abbreviation")
+	end := flags.String("end", ennet.DefaultDelimiters.End, "doc: the `delimiter` after an abbreviation")
+	closing := flags.String("close", ennet.DefaultDelimiters.Close, "doc: the `delimiter` after an expansion")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	m, err := ennet.ParseMode(*mode)
+	if err != nil {
+		fmt.Fprintln(stderr, "ennet:", err)
+		return 2
+	}
+	opts := []ennet.Option{ennet.WithMode(m), ennet.WithEscape(*escape)}
+	if *tabs {
+		opts = append(opts, ennet.WithIndent("\t"))
+	} else if *indent > 0 {
+		opts = append(opts, ennet.WithIndent(strings.Repeat(" ", *indent)))
+	}
+
+	switch command {
+	case "lsp":
+		if err := serveLSP(stdin, stdout, opts); err != nil {
+			fmt.Fprintln(stderr, "ennet:", err)
+			return 1
+		}
+		return 0
+
+	case "doc":
+		delims := ennet.Delimiters{Begin: *begin, End: *end, Close: *closing}
+		return expandDocuments(flags.Args(), *write, delims, opts, stdin, stdout, stderr)
+	}
+
+	status := 0
+	expand := func(name, abbr string) {
+		var out string
+		var err error
+		if *ast {
+			out, err = dump(abbr)
+		} else {
+			out, err = ennet.Expand(abbr, opts...)
+		}
+		if err != nil {
+			report(stderr, name, abbr, err)
+			status = 1
+			return
+		}
+		fmt.Fprintln(stdout, out)
+	}
+
+	if flags.NArg() > 0 {
+		for i, abbr := range flags.Args() {
+			expand("arg"+strconv.Itoa(i+1), abbr)
+		}
+		return status
+	}
+
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(nil, maxLineLength)
+	for line := 1; scanner.Scan(); line++ {
+		abbr := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(abbr) == "" {
+			fmt.Fprintln(stdout)
+			continue
+		}
+		expand("stdin:"+strconv.Itoa(line), abbr)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(stderr, "ennet:", err)
+		return 1
+	}
+
+	return status
+}
+
+// maxLineLength limits an abbreviation line of the standard input, like maxContentLength of the LSP.
+const maxLineLength = 64 << 20
+
+func expandDocuments(files []string, write bool, delims ennet.Delimiters, opts []ennet.Option, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(files) == 0 {
+		doc, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, "ennet:", err)
+			return 1
+		}
+		expanded, err := ennet.ExpandDocument(doc, delims, opts...)
+		if err != nil {
+			fmt.Fprintln(stderr, "stdin:"+err.Error())
+			return 1
+		}
+		stdout.Write(expanded)
+		return 0
+	}
+
+	status := 0
+	for _, file := range files {
+		doc, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(stderr, "ennet:", err)
+			status = 1
+			continue
+		}
+		expanded, err := ennet.ExpandDocument(doc, delims, opts...)
+		if err != nil {
+			fmt.Fprintln(stderr, file+":"+err.Error())
+			status = 1
+			continue
+		}
+
+		if !write {
+			stdout.Write(expanded)
+			continue
+		}
+		if bytes.Equal(doc, expanded) {
+			continue
+		}
+		if err := os.WriteFile(file, expanded, 0o666); err != nil {
+			fmt.Fprintln(stderr, "ennet:", err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func dump(abbr string) (string, error) {
+	nb := ennet.NewNodeBuilder(nil)
+	if err := ennet.Parse([]byte(abbr), &nb); err != nil {
+		return "", err
+	}
+
+	var trees []string
+	for c := nb.Root.FirstChild; c != nil; c = c.NextSibling {
+		trees = append(trees, c.Dump())
+	}
+	return strings.Join(trees, "\n"), nil
+}
+
+// report writes err like "name:col: message" followed by abbr and a caret.
+func report(w io.Writer, name, abbr string, err error) {
+	var perr *ennet.ParseError
+	if !errors.As(err, &perr) {
+		fmt.Fprintf(w, "%s: %v\n", name, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%s:%d: %v\n", name, perr.Pos, perr.Err)
+	fmt.Fprintf(w, "\t%s\n", abbr)
+
+	var caret strings.Builder
+	for i := 0; i < perr.Pos-1 && i < len(abbr); i++ {
+		if abbr[i] == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	caret.WriteString(strings.Repeat("^", max(1, perr.End-perr.Pos)))
+	fmt.Fprintf(w, "\t%s\n", caret.String())
+}
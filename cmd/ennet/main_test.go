@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shu-go/gotwant"
+)
+
+func runString(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	t.Run("Args", func(t *testing.T) {
+		status, out, _ := runString("", "ul>li*2", "br")
+		gotwant.Test(t, status, 0)
+		gotwant.Test(t, out, "<ul><li /><li /></ul>\n<br />\n")
+	})
+
+	t.Run("Stdin", func(t *testing.T) {
+		status, out, _ := runString("a\n\nb>c\n")
+		gotwant.Test(t, status, 0)
+		gotwant.Test(t, out, "<a />\n\n<b><c /></b>\n")
+
+		// longer than the default buffer of bufio.Scanner
+		long := strings.Repeat("a>", 50000) + "a"
+		status, out, _ = runString(long + "\n")
+		gotwant.Test(t, status, 0)
+		gotwant.Test(t, out == strings.Repeat("<a>", 50000)+"<a />"+strings.Repeat("</a>", 50000)+"\n", true)
+	})
+
+	t.Run("Flags", func(t *testing.T) {
+		status, out, _ := runString("", "-mode", "html", "-indent", "2", "-escape", "p>br+a{&}")
+		gotwant.Test(t, status, 0)
+		gotwant.Test(t, out, "<p>\n  <br>\n  <a href=\"\">&amp;</a>\n</p>\n")
+
+		status, out, _ = runString("", "-tabs", "p>a")
+		gotwant.Test(t, status, 0)
+		gotwant.Test(t, out, "<p>\n\t<a />\n</p>\n")
+
+		status, _, errout := runString("", "-mode", "svg", "a")
+		gotwant.Test(t, status, 2)
+		gotwant.Test(t, errout, "ennet: unknown mode \"svg\"\n")
+	})
+
+	t.Run("AST", func(t *testing.T) {
+		status, out, _ := runString("", "--ast", "a.x>b*2")
+		gotwant.Test(t, status, 0)
+		gotwant.Test(t, out, "a:element @class=x\n  b:element *2\n")
+
+		status, out, _ = runString("", "--ast", "a[title=t href=h]#x")
+		gotwant.Test(t, status, 0)
+		gotwant.Test(t, out, "a:element @title=t @href=h @id=x\n")
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		status, out, errout := runString("a\nul>li#+b\nc\n")
+		gotwant.Test(t, status, 1)
+		gotwant.Test(t, out, "<a />\n<c />\n")
+		gotwant.Test(t, errout, "stdin:2:7: id name is required\n\tul>li#+b\n\t      ^\n")
+
+		status, _, errout = runString("", "a", "b[")
+		gotwant.Test(t, status, 1)
+		gotwant.Test(t, errout, "arg2:3: AttrName as a string is required\n\tb[\n\t  ^\n")
+	})
+}
+
+func TestDoc(t *testing.T) {
+	t.Run("Stdin", func(t *testing.T) {
+		status, out, _ := runString("<p>\n  <!--ennet: a*2-->\n</p>\n", "doc", "-mode", "html")
+		gotwant.Test(t, status, 0)
+		gotwant.Test(t, out, "<p>\n  <!--ennet: a*2-->\n  <a href=\"\"></a><a href=\"\"></a>\n  <!--/ennet-->\n</p>\n")
+	})
+
+	t.Run("Files", func(t *testing.T) {
+		dir := t.TempDir()
+		good := filepath.Join(dir, "good.xml")
+		bad := filepath.Join(dir, "bad.xml")
+		os.WriteFile(good, []byte("<r>/*ennet a>b*/</r>"), 0o666)
+		os.WriteFile(bad, []byte("<r>\n/*ennet a#*/</r>"), 0o666)
+
+		status, out, errout := runString("", "doc", "-w", "-begin", "/*ennet", "-end", "*/", "-close", "/*end*/", good, bad)
+		gotwant.Test(t, status, 1)
+		gotwant.Test(t, out, "")
+		gotwant.Test(t, errout, bad+":2:11: id name is required\n")
+
+		b, _ := os.ReadFile(good)
+		gotwant.Test(t, string(b), "<r>/*ennet a>b*/<a><b /></a>/*end*/</r>")
+		b, _ = os.ReadFile(bad)
+		gotwant.Test(t, string(b), "<r>\n/*ennet a#*/</r>")
+	})
+}
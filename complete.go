@@ -0,0 +1,266 @@
+package ennet
+
+import (
+	"slices"
+	"strings"
+)
+
+// CompletionKind tells what is expected at a cursor position.
+type CompletionKind uint8
+
+const (
+	CompleteNone CompletionKind = iota
+	CompleteTag
+	CompleteID
+	CompleteClass
+	CompleteAttribute
+	CompleteAttributeValue
+	CompleteText
+	CompleteMul
+)
+
+var completionKind2String = map[CompletionKind]string{
+	CompleteNone:           "none",
+	CompleteTag:            "tag",
+	CompleteID:             "id",
+	CompleteClass:          "class",
+	CompleteAttribute:      "attribute",
+	CompleteAttributeValue: "attribute value",
+	CompleteText:           "text",
+	CompleteMul:            "multiplication",
+}
+
+func (k CompletionKind) String() string {
+	if s, found := completionKind2String[k]; found {
+		return s
+	}
+	return "???"
+}
+
+// Completion is a candidate that replaces the word being typed.
+// Pos is the 1-based position of the word, like Token.Pos.
+type Completion struct {
+	Kind CompletionKind
+	Text string
+	Pos  int
+}
+
+// Completer holds vocabularies for completion.
+//
+// Attributes is keyed by element name.
+// Attributes[""] is offered for every element.
+type Completer struct {
+	Tags       []string
+	Attributes map[string][]string
+	Classes    []string
+}
+
+// htmlCompleter is the Completer of Complete, never modified.
+var htmlCompleter = Completer{
+	Tags:       htmlTags,
+	Attributes: htmlAttributes,
+}
+
+// HTMLCompleter returns a new Completer of HTML tag and attribute names, which Complete uses.
+// The vocabularies are copies, and modifying them does not change Complete.
+func HTMLCompleter() Completer {
+	attrs := make(map[string][]string, len(htmlAttributes))
+	for name, names := range htmlAttributes {
+		attrs[name] = slices.Clone(names)
+	}
+	return Completer{
+		Tags:       slices.Clone(htmlTags),
+		Attributes: attrs,
+	}
+}
+
+// Complete returns candidates for abbr at cursor, of HTML tag and attribute names.
+// cursor is a byte offset in abbr (0 is before the first byte).
+func Complete(abbr string, cursor int) []Completion {
+	return htmlCompleter.Complete(abbr, cursor)
+}
+
+// CompletionContext tells what is expected at cursor in abbr,
+// and the position of the word at cursor (cursor+1 if it is not a word).
+// Complete and Completer.Complete return nil for CompleteID, CompleteAttributeValue, CompleteText,
+// CompleteMul and CompleteNone.
+func CompletionContext(abbr string, cursor int) (kind CompletionKind, pos int) {
+	ctx := completionContextAt(abbr, cursor)
+	return ctx.kind, ctx.pos
+}
+
+// Complete returns candidates for abbr at cursor.
+// cursor is a byte offset in abbr (0 is before the first byte).
+func (c *Completer) Complete(abbr string, cursor int) []Completion {
+	ctx := completionContextAt(abbr, cursor)
+
+	var vocab []string
+	switch ctx.kind {
+	case CompleteTag:
+		vocab = c.Tags
+	case CompleteClass:
+		vocab = c.Classes
+	case CompleteAttribute:
+		vocab = append(slices.Clone(c.Attributes[ctx.element]), c.Attributes[""]...)
+	default:
+		return nil
+	}
+
+	var result []Completion
+	for _, v := range vocab {
+		if !strings.HasPrefix(v, ctx.prefix) {
+			continue
+		}
+		if ctx.kind == CompleteAttribute && slices.Contains(ctx.written, v) {
+			continue
+		}
+		if slices.ContainsFunc(result, func(c Completion) bool { return c.Text == v }) {
+			continue
+		}
+		result = append(result, Completion{Kind: ctx.kind, Text: v, Pos: ctx.pos})
+	}
+	return result
+}
+
+type completionContext struct {
+	kind    CompletionKind
+	prefix  string
+	pos     int
+	element string
+	written []string
+}
+
+// completionContextAt walks the tokens before cursor and tells
+// what the word at cursor is.
+func completionContextAt(abbr string, cursor int) completionContext {
+	cursor = max(0, min(cursor, len(abbr)))
+
+	l := NewLexer([]byte(abbr[:cursor]))
+	defer l.Close()
+
+	var ctx completionContext
+	expect := CompleteTag // expected kind of the next word
+	inAttr := false
+
+	for {
+		tok := l.Next()
+		switch tok.Type {
+		case EOF:
+			ctx.kind = expect
+			ctx.pos = cursor + 1
+			return ctx
+
+		case ERR:
+			// an unterminated {TEXT} or "QTEXT"
+			if inAttr {
+				ctx.kind = CompleteAttributeValue
+			} else {
+				ctx.kind = CompleteText
+			}
+			ctx.pos = cursor + 1
+			return ctx
+
+		case STRING:
+			kind := expect
+			switch kind {
+			case CompleteTag:
+				ctx.element = tok.Text
+				ctx.written = nil
+				expect = CompleteNone
+			case CompleteAttribute:
+				ctx.written = append(ctx.written, tok.Text)
+			case CompleteAttributeValue:
+				expect = CompleteAttribute
+			default:
+				expect = CompleteNone
+			}
+
+			if tok.Pos-1+len(tok.Text) == cursor {
+				ctx.kind = kind
+				ctx.prefix = tok.Text
+				ctx.pos = tok.Pos
+				if kind == CompleteAttribute {
+					ctx.written = ctx.written[:len(ctx.written)-1]
+				}
+				return ctx
+			}
+
+		case CHILD, SIBLING, CLIMBUP, GROUPBEGIN:
+			expect = CompleteTag
+		case ID:
+			expect = CompleteID
+		case CLASS:
+			expect = CompleteClass
+		case MULT:
+			expect = CompleteMul
+		case ATTRBEGIN:
+			inAttr = true
+			expect = CompleteAttribute
+		case ATTREND:
+			inAttr = false
+			expect = CompleteNone
+		case EQ:
+			expect = CompleteAttributeValue
+		case QTEXT:
+			if inAttr {
+				expect = CompleteAttribute
+			} else {
+				expect = CompleteNone
+			}
+		default:
+			expect = CompleteNone
+		}
+	}
+}
+
+var htmlTags = []string{
+	"a", "abbr", "address", "area", "article", "aside", "audio",
+	"b", "base", "bdi", "bdo", "blockquote", "body", "br", "button",
+	"canvas", "caption", "cite", "code", "col", "colgroup",
+	"data", "datalist", "dd", "del", "details", "dfn", "dialog", "div", "dl", "dt",
+	"em", "embed",
+	"fieldset", "figcaption", "figure", "footer", "form",
+	"h1", "h2", "h3", "h4", "h5", "h6", "head", "header", "hgroup", "hr", "html",
+	"i", "iframe", "img", "input", "ins",
+	"kbd",
+	"label", "legend", "li", "link",
+	"main", "map", "mark", "menu", "meta", "meter",
+	"nav", "noscript",
+	"object", "ol", "optgroup", "option", "output",
+	"p", "picture", "pre", "progress",
+	"q",
+	"rp", "rt", "ruby",
+	"s", "samp", "script", "search", "section", "select", "slot", "small", "source", "span", "strong", "style", "sub", "summary", "sup",
+	"table", "tbody", "td", "template", "textarea", "tfoot", "th", "thead", "time", "title", "tr", "track",
+	"u", "ul",
+	"var", "video",
+	"wbr",
+}
+
+var htmlAttributes = map[string][]string{
+	"": {
+		"accesskey", "class", "contenteditable", "dir", "draggable", "hidden", "id",
+		"lang", "role", "style", "tabindex", "title",
+	},
+	"a":        {"download", "href", "hreflang", "ping", "referrerpolicy", "rel", "target", "type"},
+	"area":     {"alt", "coords", "download", "href", "rel", "shape", "target"},
+	"audio":    {"autoplay", "controls", "loop", "muted", "preload", "src"},
+	"button":   {"disabled", "form", "name", "type", "value"},
+	"form":     {"accept-charset", "action", "autocomplete", "enctype", "method", "name", "novalidate", "target"},
+	"iframe":   {"allow", "height", "loading", "name", "referrerpolicy", "sandbox", "src", "srcdoc", "width"},
+	"img":      {"alt", "crossorigin", "decoding", "height", "loading", "sizes", "src", "srcset", "width"},
+	"input":    {"accept", "autocomplete", "checked", "disabled", "form", "list", "max", "maxlength", "min", "minlength", "multiple", "name", "pattern", "placeholder", "readonly", "required", "size", "step", "type", "value"},
+	"label":    {"for", "form"},
+	"link":     {"as", "crossorigin", "href", "media", "rel", "sizes", "type"},
+	"meta":     {"charset", "content", "http-equiv", "name"},
+	"ol":       {"reversed", "start", "type"},
+	"option":   {"disabled", "label", "selected", "value"},
+	"script":   {"async", "crossorigin", "defer", "integrity", "nomodule", "src", "type"},
+	"select":   {"autocomplete", "disabled", "form", "multiple", "name", "required", "size"},
+	"source":   {"media", "sizes", "src", "srcset", "type"},
+	"td":       {"colspan", "headers", "rowspan"},
+	"textarea": {"cols", "disabled", "form", "maxlength", "name", "placeholder", "readonly", "required", "rows", "wrap"},
+	"th":       {"abbr", "colspan", "headers", "rowspan", "scope"},
+	"time":     {"datetime"},
+	"video":    {"autoplay", "controls", "height", "loop", "muted", "playsinline", "poster", "preload", "src", "width"},
+}
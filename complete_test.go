@@ -0,0 +1,102 @@
+package ennet_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/shu-go/ennet"
+	"github.com/shu-go/gotwant"
+)
+
+func texts(cs []ennet.Completion) []string {
+	var s []string
+	for _, c := range cs {
+		s = append(s, c.Text)
+	}
+	return s
+}
+
+func TestComplete(t *testing.T) {
+	c := ennet.Completer{
+		Tags: []string{"li", "link", "ul"},
+		Attributes: map[string][]string{
+			"":  {"id", "title"},
+			"a": {"href", "target"},
+		},
+		Classes: []string{"item", "item-active", "nav"},
+	}
+
+	t.Run("Tag", func(t *testing.T) {
+		cs := c.Complete("ul>li", 5)
+		gotwant.Test(t, texts(cs), []string{"li", "link"})
+		gotwant.Test(t, cs[0].Kind, ennet.CompleteTag)
+		gotwant.Test(t, cs[0].Pos, 4)
+
+		cs = c.Complete("ul+", 3)
+		gotwant.Test(t, texts(cs), []string{"li", "link", "ul"})
+		gotwant.Test(t, cs[0].Pos, 4)
+
+		gotwant.Test(t, texts(c.Complete("", 0)), []string{"li", "link", "ul"})
+		gotwant.Test(t, texts(c.Complete("(u", 2)), []string{"ul"})
+	})
+
+	t.Run("Class", func(t *testing.T) {
+		cs := c.Complete("ul>li.it", 8)
+		gotwant.Test(t, texts(cs), []string{"item", "item-active"})
+		gotwant.Test(t, cs[0].Kind, ennet.CompleteClass)
+		gotwant.Test(t, cs[0].Pos, 7)
+
+		// the cursor is in the middle
+		gotwant.Test(t, texts(c.Complete("ul>li.n>a", 7)), []string{"nav"})
+	})
+
+	t.Run("Attribute", func(t *testing.T) {
+		cs := c.Complete("a[", 2)
+		gotwant.Test(t, texts(cs), []string{"href", "target", "id", "title"})
+		gotwant.Test(t, cs[0].Kind, ennet.CompleteAttribute)
+
+		gotwant.Test(t, texts(c.Complete("a[t", 3)), []string{"target", "title"})
+		gotwant.Test(t, texts(c.Complete("a[href=x ", 9)), []string{"target", "id", "title"})
+		gotwant.Test(t, texts(c.Complete(`a[href="x" title `, 17)), []string{"target", "id"})
+		gotwant.Test(t, texts(c.Complete("li[", 3)), []string{"id", "title"})
+	})
+
+	t.Run("Nothing", func(t *testing.T) {
+		gotwant.Test(t, len(c.Complete("a[href=", 7)), 0)
+		gotwant.Test(t, len(c.Complete("a{Click ", 8)), 0)
+		gotwant.Test(t, len(c.Complete("a*", 2)), 0)
+		gotwant.Test(t, len(c.Complete("a#", 2)), 0)
+		gotwant.Test(t, len(c.Complete("a[x]", 4)), 0)
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		for _, c := range []struct {
+			abbr string
+			kind ennet.CompletionKind
+			pos  int
+		}{
+			{"ul>li", ennet.CompleteTag, 4},
+			{"a[href=", ennet.CompleteAttributeValue, 8},
+			{"a{Click ", ennet.CompleteText, 9},
+			{"a*3", ennet.CompleteMul, 3},
+			{"a#", ennet.CompleteID, 3},
+			{"a[x]", ennet.CompleteNone, 5},
+		} {
+			kind, pos := ennet.CompletionContext(c.abbr, len(c.abbr))
+			gotwant.Test(t, kind, c.kind, gotwant.Desc(c.abbr))
+			gotwant.Test(t, pos, c.pos, gotwant.Desc(c.abbr))
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		cs := ennet.Complete("div>tb", 6)
+		gotwant.Test(t, texts(cs), []string{"tbody"})
+
+		hc := ennet.HTMLCompleter()
+		gotwant.Test(t, texts(hc.Complete("div>tb", 6)), []string{"tbody"})
+		hc.Tags[slices.Index(hc.Tags, "tbody")] = "x"
+		hc.Attributes["a"][0] = "x"
+		gotwant.Test(t, texts(ennet.Complete("div>tb", 6)), []string{"tbody"})
+		gotwant.Test(t, texts(ennet.Complete("a[hr", 4)), []string{"href", "hreflang"})
+	})
+}
@@ -0,0 +1,180 @@
+package ennet
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CounterFormat formats the number n of a numbering placeholder.
+// width is the count of "$" ($$$ is 3), for padding if the format has a zero.
+type CounterFormat func(n, width int) string
+
+var (
+	counterFormatsMu sync.RWMutex
+	counterFormats   = map[string]CounterFormat{
+		"decimal":   formatDecimal,
+		"alpha":     formatAlpha('a'),
+		"ALPHA":     formatAlpha('A'),
+		"roman":     formatRoman(true),
+		"ROMAN":     formatRoman(false),
+		"kanji":     formatKanji,
+		"circled":   formatCircled,
+		"fullwidth": formatFullwidth,
+	}
+)
+
+// RegisterCounterFormat adds f as the format name, used as $|name or ${$*2|name}.
+// name consists of ASCII letters, digits, "_" and "-".
+// A format of the same name is replaced.
+//
+// The formats decimal, alpha (a, b, ..., z, aa), ALPHA, roman (i, ii, iii), ROMAN,
+// kanji (一, 二, 三), circled (①, ②, ③) and fullwidth (１, ２, ３) are registered by default.
+// Numbers that a format cannot represent, such as 0 in roman, are written in decimal.
+func RegisterCounterFormat(name string, f CounterFormat) {
+	if counterFormatLen(name) != len(name) || name == "" {
+		panic("ennet: invalid counter format name " + strconv.Quote(name))
+	}
+	if f == nil {
+		panic("ennet: nil counter format " + name)
+	}
+
+	counterFormatsMu.Lock()
+	counterFormats[name] = f
+	counterFormatsMu.Unlock()
+}
+
+func lookupCounterFormat(name string) (CounterFormat, bool) {
+	counterFormatsMu.RLock()
+	f, found := counterFormats[name]
+	counterFormatsMu.RUnlock()
+	return f, found
+}
+
+// counterFormatLen returns the length of the format name (letters, digits, "_" and "-") at the start of s.
+func counterFormatLen(s string) int {
+	i := 0
+	for i < len(s) && (isVariableNameByte(s[i]) || s[i] == '-') {
+		i++
+	}
+	return i
+}
+
+func formatDecimal(n, width int) string {
+	s := strconv.Itoa(n)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	return s
+}
+
+func formatAlpha(a byte) CounterFormat {
+	return func(n, width int) string {
+		if n <= 0 {
+			return formatDecimal(n, width)
+		}
+
+		var buf [16]byte
+		i := len(buf)
+		for n > 0 {
+			n--
+			i--
+			buf[i] = a + byte(n%26)
+			n /= 26
+		}
+		return string(buf[i:])
+	}
+}
+
+func formatRoman(lower bool) CounterFormat {
+	numerals := []struct {
+		value int
+		s     string
+	}{
+		{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"},
+		{100, "C"}, {90, "XC"}, {50, "L"}, {40, "XL"},
+		{10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+	}
+
+	return func(n, width int) string {
+		if n <= 0 || n >= 4000 {
+			return formatDecimal(n, width)
+		}
+
+		var s strings.Builder
+		for _, num := range numerals {
+			for n >= num.value {
+				s.WriteString(num.s)
+				n -= num.value
+			}
+		}
+		if lower {
+			return strings.ToLower(s.String())
+		}
+		return s.String()
+	}
+}
+
+func formatKanji(n, width int) string {
+	if n < 0 {
+		return formatDecimal(n, width)
+	}
+	if n == 0 {
+		return "〇"
+	}
+
+	digits := []string{"", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+	units := []string{"", "十", "百", "千"}
+	groups := []string{"", "万", "億", "兆", "京"}
+
+	var s string
+	for g := 0; n > 0 && g < len(groups); g++ {
+		group := n % 10000
+		n /= 10000
+		if group == 0 {
+			continue
+		}
+
+		var gs string
+		for u := 0; group > 0; u++ {
+			d := group % 10
+			group /= 10
+			switch {
+			case d == 0:
+			case d == 1 && u > 0:
+				gs = units[u] + gs // 十, not 一十
+			default:
+				gs = digits[d] + units[u] + gs
+			}
+		}
+		s = gs + groups[g] + s
+	}
+	return s
+}
+
+func formatCircled(n, width int) string {
+	switch {
+	case n == 0:
+		return "⓪"
+	case 1 <= n && n <= 20:
+		return string(rune(0x2460 + n - 1))
+	case 21 <= n && n <= 35:
+		return string(rune(0x3251 + n - 21))
+	case 36 <= n && n <= 50:
+		return string(rune(0x32B1 + n - 36))
+	default:
+		return formatDecimal(n, width)
+	}
+}
+
+func formatFullwidth(n, width int) string {
+	var s strings.Builder
+	for _, c := range formatDecimal(n, width) {
+		if c == '-' {
+			s.WriteRune('－')
+		} else {
+			s.WriteRune(0xFF10 + c - '0')
+		}
+	}
+	return s.String()
+}
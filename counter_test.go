@@ -0,0 +1,59 @@
+package ennet_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/shu-go/ennet"
+	"github.com/shu-go/gotwant"
+)
+
+func TestCounterFormat(t *testing.T) {
+	expand := func(abbr string) string {
+		t.Helper()
+		s, err := ennet.Expand(abbr)
+		gotwant.TestError(t, err, nil)
+		return s
+	}
+
+	t.Run("Builtin", func(t *testing.T) {
+		gotwant.Test(t, expand(`p{$|alpha}*3`), `<p>a</p><p>b</p><p>c</p>`)
+		gotwant.Test(t, expand(`p{$@26|ALPHA}*2`), `<p>Z</p><p>AA</p>`)
+		gotwant.Test(t, expand(`p{$@3|roman}*2`), `<p>iii</p><p>iv</p>`)
+		gotwant.Test(t, expand(`p{$@1994|ROMAN}*1`), `<p>MCMXCIV</p>`)
+		gotwant.Test(t, expand(`p{$@19|circled}*3`), `<p>⑲</p><p>⑳</p><p>㉑</p>`)
+		gotwant.Test(t, expand(`p{$@-|kanji}*3`), `<p>三</p><p>二</p><p>一</p>`)
+		gotwant.Test(t, expand(`p{$$|fullwidth}*1`), `<p>０１</p>`)
+		gotwant.Test(t, expand(`p{$$|decimal}*1`), `<p>01</p>`)
+	})
+
+	t.Run("Expression", func(t *testing.T) {
+		gotwant.Test(t, expand(`p{${$*10|roman}}*2`), `<p>x</p><p>xx</p>`)
+		gotwant.Test(t, expand(`p{${$-1|roman}}*2`), `<p>0</p><p>i</p>`)
+	})
+
+	t.Run("Kanji", func(t *testing.T) {
+		for n, want := range map[int]string{
+			10:        "十",
+			11:        "十一",
+			20:        "二十",
+			105:       "百五",
+			1000:      "千",
+			2024:      "二千二十四",
+			10000:     "一万",
+			120000003: "一億二千万三",
+		} {
+			gotwant.Test(t, expand(`p{$@`+strconv.Itoa(n)+`|kanji}*1`), `<p>`+want+`</p>`)
+		}
+	})
+
+	t.Run("Register", func(t *testing.T) {
+		ennet.RegisterCounterFormat("step-x", func(n, width int) string {
+			return strconv.Itoa(n) + "x" + strconv.Itoa(width)
+		})
+		gotwant.Test(t, expand(`p.s$$|step-x*2`), `<p class="s1x2" /><p class="s2x2" />`)
+
+		_, err := ennet.Expand(`p{$|nothing}*2`)
+		gotwant.TestError(t, err, "unknown counter format nothing")
+	})
+}
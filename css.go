@@ -0,0 +1,377 @@
+package ennet
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// ExpandCSS expands Emmet CSS abbreviation s into declarations.
+//
+//	m10+p5-10   margin: 10px; padding: 5px 10px;
+//	bd1-s#f00   border: 1px solid #f00;
+//	w100p       width: 100%;
+//	pos:a       position: absolute;
+//	m-10--5!    margin: -10px -5px !important;
+//
+// A property is the longest abbreviation in the table that the leading letters start with,
+// and the rest of the letters is the first value.
+// Values are separated by "-", and "--" (or "-" at first) makes a number negative.
+// Integers are in px and decimals in em, unless the unit is given (p for %, e for em, x for ex, r for rem)
+// or the property is unitless.
+//
+// Declarations are separated by a space, or by a line break with WithIndent.
+func ExpandCSS(s string, opts ...Option) (string, error) {
+	o := &noOptions
+	if len(opts) > 0 {
+		o = &options{}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+
+	b := expandBufPool.Get().(*bytes.Buffer)
+	b.Reset()
+	defer expandBufPool.Put(b)
+
+	l := cssLexer{in: s}
+	for {
+		if b.Len() > 0 {
+			if o.indent != "" {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		if err := l.declaration(b); err != nil {
+			return "", err
+		}
+		if o.maxOutput > 0 && b.Len() > o.maxOutput {
+			return "", ErrOutputLimit
+		}
+
+		tok := l.next()
+		if tok.typ == cssEOF {
+			break
+		}
+		if tok.typ != cssPlus {
+			return "", l.errorAt(tok, errors.New("parsing failed because of extra "+tok.text))
+		}
+	}
+	return b.String(), nil
+}
+
+type cssTokenType uint8
+
+const (
+	cssEOF       cssTokenType = iota
+	cssWord                   // letters
+	cssNumber                 // 10, 1.5, .5
+	cssColor                  // #f00
+	cssHyphen                 // -
+	cssColon                  // :
+	cssPlus                   // +
+	cssImportant              // !
+	cssOther
+)
+
+type cssToken struct {
+	typ  cssTokenType
+	text string
+	pos  int // 1-based like Token.Pos
+}
+
+// cssLexer splits a CSS abbreviation into cssTokens, one token of lookahead.
+type cssLexer struct {
+	in     string
+	offset int
+
+	peeked *cssToken
+}
+
+func (l *cssLexer) peek() cssToken {
+	if l.peeked == nil {
+		tok := l.scan()
+		l.peeked = &tok
+	}
+	return *l.peeked
+}
+
+func (l *cssLexer) next() cssToken {
+	tok := l.peek()
+	l.peeked = nil
+	return tok
+}
+
+func (l *cssLexer) scan() cssToken {
+	for l.offset < len(l.in) && isSpace(l.in[l.offset]) {
+		l.offset++
+	}
+	if l.offset >= len(l.in) {
+		return cssToken{typ: cssEOF, pos: l.offset + 1}
+	}
+
+	start := l.offset
+	c := l.in[l.offset]
+	l.offset++
+
+	typ := cssOther
+	switch {
+	case c == '-':
+		typ = cssHyphen
+	case c == ':':
+		typ = cssColon
+	case c == '+':
+		typ = cssPlus
+	case c == '!':
+		typ = cssImportant
+	case c == '#':
+		typ = cssColor
+		for l.offset < len(l.in) && isHexDigit(l.in[l.offset]) {
+			l.offset++
+		}
+	case isLetter(c):
+		typ = cssWord
+		for l.offset < len(l.in) && isLetter(l.in[l.offset]) {
+			l.offset++
+		}
+	case isDigit(c) || c == '.':
+		typ = cssNumber
+		for l.offset < len(l.in) && (isDigit(l.in[l.offset]) || l.in[l.offset] == '.') {
+			l.offset++
+		}
+	}
+	return cssToken{typ: typ, text: l.in[start:l.offset], pos: start + 1}
+}
+
+func (l *cssLexer) errorAt(tok cssToken, err error) *ParseError {
+	return &ParseError{Pos: tok.pos, End: tok.pos + max(len(tok.text), 1), Err: err}
+}
+
+// declaration writes a declaration, property and values.
+func (l *cssLexer) declaration(w *bytes.Buffer) error {
+	tok := l.next()
+	if tok.typ != cssWord {
+		return l.errorAt(tok, errors.New("a CSS property is required"))
+	}
+
+	abbr, prop := longestProperty(tok.text)
+	if prop == "" {
+		return l.errorAt(tok, errors.New("unknown CSS property "+tok.text))
+	}
+	w.WriteString(prop)
+	w.WriteString(":")
+
+	nvalues := 0
+	value := func(v string) {
+		w.WriteByte(' ')
+		w.WriteString(v)
+		nvalues++
+	}
+
+	if rest := tok.text[len(abbr):]; rest != "" {
+		value(cssKeyword(abbr, rest))
+	} else if l.peek().typ == cssColon {
+		l.next()
+	}
+
+	negative := false
+	for {
+		tok := l.peek()
+		switch tok.typ {
+		case cssHyphen:
+			l.next()
+			if negative || nvalues == 0 {
+				negative = true // "-" before the first value
+			} else {
+				negative = l.peek().typ == cssHyphen
+				if negative {
+					l.next()
+				}
+			}
+			continue
+
+		case cssNumber:
+			l.next()
+			num := tok.text
+			if strings.Count(num, ".") > 1 {
+				return l.errorAt(tok, errors.New("invalid number "+num))
+			}
+			if strings.HasPrefix(num, ".") {
+				num = "0" + num
+			}
+			if negative && strings.Trim(num, "0.") != "" {
+				num = "-" + num
+			}
+
+			unit := ""
+			if u := l.peek(); u.typ == cssWord && u.pos == tok.pos+len(tok.text) {
+				l.next()
+				unit = u.text
+				if alias, found := cssUnitAliases[unit]; found {
+					unit = alias
+				}
+			} else if !cssUnitless[abbr] && strings.Trim(num, "-0.") != "" {
+				unit = "px"
+				if strings.Contains(num, ".") {
+					unit = "em"
+				}
+			}
+			value(num + unit)
+
+		case cssColor:
+			l.next()
+			value(cssColorValue(tok.text))
+
+		case cssWord:
+			l.next()
+			value(cssKeyword(abbr, tok.text))
+
+		case cssImportant:
+			l.next()
+			w.WriteString(" !important")
+			w.WriteString(";")
+			return nil
+
+		default:
+			if nvalues == 0 {
+				return l.errorAt(tok, errors.New("a value of "+prop+" is required"))
+			}
+			w.WriteString(";")
+			return nil
+		}
+		negative = false
+	}
+}
+
+// longestProperty returns the longest property abbreviation that s starts with.
+func longestProperty(s string) (abbr, prop string) {
+	for i := len(s); i > 0; i-- {
+		if prop, found := cssProperties[s[:i]]; found {
+			return s[:i], prop
+		}
+	}
+	return "", ""
+}
+
+func cssKeyword(prop, abbr string) string {
+	if kw, found := cssPropertyKeywords[prop][abbr]; found {
+		return kw
+	}
+	if kw, found := cssKeywords[abbr]; found {
+		return kw
+	}
+	return abbr
+}
+
+// cssColorValue expands #f to #fff and #ab to #ababab.
+func cssColorValue(c string) string {
+	hex := strings.ToLower(c[1:])
+	switch len(hex) {
+	case 0:
+		return "#000"
+	case 1, 2:
+		return "#" + strings.Repeat(hex, 3)
+	default:
+		return "#" + hex
+	}
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+var cssProperties = map[string]string{
+	"m": "margin", "mt": "margin-top", "mr": "margin-right", "mb": "margin-bottom", "ml": "margin-left",
+	"p": "padding", "pt": "padding-top", "pr": "padding-right", "pb": "padding-bottom", "pl": "padding-left",
+	"w": "width", "h": "height", "maw": "max-width", "mah": "max-height", "miw": "min-width", "mih": "min-height",
+	"t": "top", "r": "right", "b": "bottom", "l": "left", "z": "z-index",
+	"pos": "position", "d": "display", "fl": "float", "cl": "clear", "v": "visibility",
+	"ov": "overflow", "ovx": "overflow-x", "ovy": "overflow-y", "bxz": "box-sizing", "cur": "cursor",
+	"op": "opacity", "zm": "zoom",
+	"c": "color", "bg": "background", "bgc": "background-color", "bgi": "background-image",
+	"bd": "border", "bdt": "border-top", "bdr": "border-right", "bdb": "border-bottom", "bdl": "border-left",
+	"bdc": "border-color", "bds": "border-style", "bdw": "border-width", "bdrs": "border-radius",
+	"ol": "outline", "bxsh": "box-shadow",
+	"ff": "font-family", "fz": "font-size", "fw": "font-weight", "fs": "font-style", "lh": "line-height",
+	"ta": "text-align", "td": "text-decoration", "tt": "text-transform", "ti": "text-indent",
+	"va": "vertical-align", "ws": "white-space", "ls": "letter-spacing", "wos": "word-spacing",
+	"fx": "flex", "fxd": "flex-direction", "fxw": "flex-wrap", "fxg": "flex-grow", "fxsh": "flex-shrink", "fxb": "flex-basis",
+	"jc": "justify-content", "ai": "align-items", "ac": "align-content", "as": "align-self", "ord": "order",
+	"g": "gap", "rg": "row-gap", "cg": "column-gap", "gtc": "grid-template-columns", "gtr": "grid-template-rows",
+	"trs": "transition", "trf": "transform", "anim": "animation",
+}
+
+var cssUnitless = map[string]bool{
+	"z": true, "op": true, "zm": true, "fw": true, "lh": true,
+	"fx": true, "fxg": true, "fxsh": true, "ord": true,
+}
+
+var cssUnitAliases = map[string]string{
+	"p": "%",
+	"e": "em",
+	"x": "ex",
+	"r": "rem",
+}
+
+var cssKeywords = map[string]string{
+	"a":   "auto",
+	"n":   "none",
+	"i":   "inherit",
+	"ini": "initial",
+	"t":   "transparent",
+	"s":   "solid",
+	"ds":  "dashed",
+	"dt":  "dotted",
+	"db":  "double",
+	"c":   "center",
+	"l":   "left",
+	"r":   "right",
+	"h":   "hidden",
+	"v":   "visible",
+	"no":  "normal",
+}
+
+var borderKeywords = map[string]string{"s": "solid", "d": "dashed", "dt": "dotted", "db": "double", "n": "none"}
+
+var cssPropertyKeywords = map[string]map[string]string{
+	"pos":  {"s": "static", "r": "relative", "a": "absolute", "f": "fixed", "st": "sticky"},
+	"d":    {"n": "none", "b": "block", "i": "inline", "ib": "inline-block", "f": "flex", "if": "inline-flex", "g": "grid", "t": "table"},
+	"fl":   {"l": "left", "r": "right", "n": "none"},
+	"cl":   {"l": "left", "r": "right", "b": "both", "n": "none"},
+	"ov":   {"h": "hidden", "v": "visible", "a": "auto", "s": "scroll"},
+	"ovx":  {"h": "hidden", "v": "visible", "a": "auto", "s": "scroll"},
+	"ovy":  {"h": "hidden", "v": "visible", "a": "auto", "s": "scroll"},
+	"bxz":  {"cb": "content-box", "bb": "border-box"},
+	"cur":  {"p": "pointer", "d": "default", "a": "auto", "t": "text", "m": "move"},
+	"fw":   {"n": "normal", "b": "bold", "br": "bolder", "l": "lighter"},
+	"fs":   {"n": "normal", "i": "italic", "o": "oblique"},
+	"ta":   {"l": "left", "r": "right", "c": "center", "j": "justify"},
+	"td":   {"n": "none", "u": "underline", "o": "overline", "l": "line-through"},
+	"tt":   {"n": "none", "u": "uppercase", "l": "lowercase", "c": "capitalize"},
+	"va":   {"t": "top", "m": "middle", "b": "bottom", "bl": "baseline"},
+	"ws":   {"n": "normal", "nw": "nowrap", "p": "pre", "pw": "pre-wrap"},
+	"fxd":  {"r": "row", "rr": "row-reverse", "c": "column", "cr": "column-reverse"},
+	"fxw":  {"n": "nowrap", "w": "wrap", "wr": "wrap-reverse"},
+	"jc":   {"s": "flex-start", "e": "flex-end", "c": "center", "sb": "space-between", "sa": "space-around", "se": "space-evenly"},
+	"ai":   {"s": "flex-start", "e": "flex-end", "c": "center", "b": "baseline", "st": "stretch"},
+	"ac":   {"s": "flex-start", "e": "flex-end", "c": "center", "sb": "space-between", "sa": "space-around", "st": "stretch"},
+	"as":   {"s": "flex-start", "e": "flex-end", "c": "center", "b": "baseline", "st": "stretch", "a": "auto"},
+	"v":    {"v": "visible", "h": "hidden", "c": "collapse"},
+	"bd":   borderKeywords,
+	"bdt":  borderKeywords,
+	"bdr":  borderKeywords,
+	"bdb":  borderKeywords,
+	"bdl":  borderKeywords,
+	"bds":  borderKeywords,
+	"ol":   borderKeywords,
+	"bxsh": {"n": "none"},
+}
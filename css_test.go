@@ -0,0 +1,74 @@
+package ennet_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shu-go/ennet"
+	"github.com/shu-go/gotwant"
+)
+
+func TestExpandCSS(t *testing.T) {
+	t.Run("Values", func(t *testing.T) {
+		for _, c := range []struct{ abbr, want string }{
+			{`m10+p5-10`, `margin: 10px; padding: 5px 10px;`},
+			{`bd1-s#f00`, `border: 1px solid #f00;`},
+			{`w100p`, `width: 100%;`},
+			{`m0-a`, `margin: 0 auto;`},
+			{`m-10--5`, `margin: -10px -5px;`},
+			{`fz1.5+lh.5`, `font-size: 1.5em; line-height: 0.5;`},
+			{`w10rem+h50vh+mt2e+mb1x`, `width: 10rem; height: 50vh; margin-top: 2em; margin-bottom: 1ex;`},
+			{`z10+op.5+fw700`, `z-index: 10; opacity: 0.5; font-weight: 700;`},
+			{`c#F+bgc#ab+bdc#ABCDEF`, `color: #fff; background-color: #ababab; border-color: #abcdef;`},
+			{`m10!`, `margin: 10px !important;`},
+		} {
+			s, err := ennet.ExpandCSS(c.abbr)
+			gotwant.TestError(t, err, nil)
+			gotwant.Test(t, s, c.want)
+		}
+	})
+
+	t.Run("Keywords", func(t *testing.T) {
+		for _, c := range []struct{ abbr, want string }{
+			{`dn`, `display: none;`},
+			{`dib`, `display: inline-block;`},
+			{`pos:a`, `position: absolute;`},
+			{`posr`, `position: relative;`},
+			{`fwb`, `font-weight: bold;`},
+			{`tac`, `text-align: center;`},
+			{`jcsb`, `justify-content: space-between;`},
+			{`ovh`, `overflow: hidden;`},
+			{`bdn`, `border: none;`},
+			{`ff-serif`, `font-family: serif;`},
+		} {
+			s, err := ennet.ExpandCSS(c.abbr)
+			gotwant.TestError(t, err, nil)
+			gotwant.Test(t, s, c.want)
+		}
+	})
+
+	t.Run("Indent", func(t *testing.T) {
+		s, err := ennet.ExpandCSS(`m10+p5`, ennet.WithIndent("  "))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, "margin: 10px;\npadding: 5px;")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := ennet.ExpandCSS(`xyz10`)
+		gotwant.TestError(t, err, "unknown CSS property xyz")
+
+		_, err = ennet.ExpandCSS(`m10+`)
+		gotwant.TestError(t, err, "a CSS property is required")
+
+		_, err = ennet.ExpandCSS(`m`)
+		gotwant.TestError(t, err, "a value of margin is required")
+
+		_, err = ennet.ExpandCSS(`w1.2.3`)
+		gotwant.TestError(t, err, "invalid number 1.2.3")
+
+		_, err = ennet.ExpandCSS(`m10>p5`)
+		var perr *ennet.ParseError
+		gotwant.Test(t, errors.As(err, &perr), true)
+		gotwant.Test(t, perr.Pos, 4)
+	})
+}
@@ -0,0 +1,158 @@
+package ennet
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+// Delimiters mark abbreviations embedded in a document.
+//
+// An abbreviation is written between Begin and End,
+// and its expansion is written after End, followed by Close.
+// Since the markers are kept, the document can be expanded again.
+type Delimiters struct {
+	Begin, End string
+	Close      string
+}
+
+// DefaultDelimiters are for XML and HTML documents.
+//
+//	<!--ennet: ul>li*2 -->
+//	<ul><li /><li /></ul>
+//	<!--/ennet-->
+var DefaultDelimiters = Delimiters{
+	Begin: "<!--ennet:",
+	End:   "-->",
+	Close: "<!--/ennet-->",
+}
+
+// DocumentError is an error in ExpandDocument.
+// Line and Col are 1-based, and Col counts bytes.
+type DocumentError struct {
+	Line, Col int
+	Err       error
+}
+
+func (e *DocumentError) Error() string {
+	return strconv.Itoa(e.Line) + ":" + strconv.Itoa(e.Col) + ": " + e.Err.Error()
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// ExpandDocument expands abbreviations marked by delims in doc.
+//
+// If a marker is alone on its line, the expansion is written on the following lines,
+// indented as the marker.
+// Otherwise the expansion is written right after the marker.
+// The expansion written by a previous run, up to Close, is replaced.
+func ExpandDocument(doc []byte, delims Delimiters, opts ...Option) ([]byte, error) {
+	if delims.Begin == "" || delims.End == "" || delims.Close == "" {
+		return nil, errors.New("delimiters must not be empty")
+	}
+
+	begin, end, closing := []byte(delims.Begin), []byte(delims.End), []byte(delims.Close)
+
+	var out bytes.Buffer
+	last := 0
+	for {
+		b := bytes.Index(doc[last:], begin)
+		if b == -1 {
+			break
+		}
+		b += last
+
+		abbrStart := b + len(begin)
+		e := bytes.Index(doc[abbrStart:], end)
+		if e == -1 {
+			return nil, docError(doc, b, errors.New(strconv.Quote(delims.Begin)+" without "+strconv.Quote(delims.End)))
+		}
+		abbrEnd := abbrStart + e
+		markerEnd := abbrEnd + len(end)
+
+		// trim spaces
+		for abbrStart < abbrEnd && isSpace(doc[abbrStart]) {
+			abbrStart++
+		}
+		for abbrEnd > abbrStart && isSpace(doc[abbrEnd-1]) {
+			abbrEnd--
+		}
+
+		expanded, err := Expand(string(doc[abbrStart:abbrEnd]), opts...)
+		if err != nil {
+			var perr *ParseError
+			if errors.As(err, &perr) {
+				return nil, docError(doc, abbrStart+perr.Pos-1, perr.Err)
+			}
+			return nil, docError(doc, abbrStart, err)
+		}
+
+		lineStart := bytes.LastIndexByte(doc[:b], '\n') + 1
+		indent := doc[lineStart:b]
+		lineEnd := len(doc)
+		if i := bytes.IndexByte(doc[markerEnd:], '\n'); i != -1 {
+			lineEnd = markerEnd + i
+		}
+		block := len(bytes.TrimSpace(indent)) == 0 && len(bytes.TrimSpace(doc[markerEnd:lineEnd])) == 0
+
+		out.Write(doc[last:markerEnd])
+		if block {
+			// the marker line, and the expansion on the following lines
+			newline := []byte("\n")
+			if lineEnd > markerEnd && doc[lineEnd-1] == '\r' {
+				lineEnd--
+				newline = []byte("\r\n")
+			}
+			out.Write(doc[markerEnd:lineEnd])
+
+			for line := range bytes.SplitSeq([]byte(expanded), []byte("\n")) {
+				out.Write(newline)
+				out.Write(indent)
+				out.Write(line)
+			}
+			out.Write(newline)
+			out.Write(indent)
+			out.Write(closing)
+
+			last = lineEnd
+			if c := regionEnd(doc, lineEnd, begin, closing); c != -1 {
+				last = c
+			}
+		} else {
+			out.WriteString(expanded)
+			out.Write(closing)
+
+			last = markerEnd
+			if c := regionEnd(doc, markerEnd, begin, closing); c != -1 {
+				last = c
+			}
+		}
+	}
+	out.Write(doc[last:])
+
+	return out.Bytes(), nil
+}
+
+// regionEnd returns the end of closing of the expansion written from start,
+// or -1 if there is no expansion.
+func regionEnd(doc []byte, start int, begin, closing []byte) int {
+	c := bytes.Index(doc[start:], closing)
+	if c == -1 {
+		return -1
+	}
+	if b := bytes.Index(doc[start:], begin); b != -1 && b < c {
+		return -1 // closing belongs to another marker
+	}
+	return start + c + len(closing)
+}
+
+func docError(doc []byte, offset int, err error) *DocumentError {
+	lineStart := bytes.LastIndexByte(doc[:offset], '\n') + 1
+	return &DocumentError{
+		Line: bytes.Count(doc[:offset], []byte("\n")) + 1,
+		Col:  offset - lineStart + 1,
+		Err:  err,
+	}
+}
@@ -0,0 +1,76 @@
+package ennet_test
+
+import (
+	"testing"
+
+	"github.com/shu-go/ennet"
+	"github.com/shu-go/gotwant"
+)
+
+func TestExpandDocument(t *testing.T) {
+	t.Run("Block", func(t *testing.T) {
+		doc := `<html>
+  <body>
+    <!--ennet: ul>li.item$*2 -->
+  </body>
+</html>`
+		want := `<html>
+  <body>
+    <!--ennet: ul>li.item$*2 -->
+    <ul>
+      <li class="item1"></li>
+      <li class="item2"></li>
+    </ul>
+    <!--/ennet-->
+  </body>
+</html>`
+
+		got, err := ennet.ExpandDocument([]byte(doc), ennet.DefaultDelimiters, ennet.WithMode(ennet.HTML), ennet.WithIndent("  "))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, string(got), want)
+
+		// regenerate
+		got, err = ennet.ExpandDocument(got, ennet.DefaultDelimiters, ennet.WithMode(ennet.HTML), ennet.WithIndent("  "))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, string(got), want)
+	})
+
+	t.Run("Inline", func(t *testing.T) {
+		doc := "<p><!--ennet:b{x}+i{y}--> and <!--ennet:br--></p>"
+		want := "<p><!--ennet:b{x}+i{y}--><b>x</b><i>y</i><!--/ennet--> and <!--ennet:br--><br /><!--/ennet--></p>"
+
+		got, err := ennet.ExpandDocument([]byte(doc), ennet.DefaultDelimiters)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, string(got), want)
+
+		got, err = ennet.ExpandDocument(got, ennet.DefaultDelimiters)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, string(got), want)
+	})
+
+	t.Run("Delimiters", func(t *testing.T) {
+		delims := ennet.Delimiters{Begin: "{{", End: "}}", Close: "{{end}}"}
+		doc := "\t{{ a*2 }}\r\nrest\r\n"
+		want := "\t{{ a*2 }}\r\n\t<a /><a />\r\n\t{{end}}\r\nrest\r\n"
+
+		got, err := ennet.ExpandDocument([]byte(doc), delims)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, string(got), want)
+
+		got, err = ennet.ExpandDocument(got, delims)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, string(got), want)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		doc := "<a>\n  <!--ennet: ul>li# -->\n</a>"
+		_, err := ennet.ExpandDocument([]byte(doc), ennet.DefaultDelimiters)
+		gotwant.TestError(t, err, "2:20: id name is required")
+		derr := err.(*ennet.DocumentError)
+		gotwant.Test(t, derr.Line, 2)
+		gotwant.Test(t, derr.Col, 20)
+
+		_, err = ennet.ExpandDocument([]byte("a\n b <!--ennet: a"), ennet.DefaultDelimiters)
+		gotwant.TestError(t, err, `2:4: "<!--ennet:" without "-->"`)
+	})
+}
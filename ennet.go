@@ -1,14 +1,32 @@
 // Package ennet parses Emmet-like abbreviations and expands them into XML.
 //
+// # Placeholders
+//
+// Names, attribute values and texts may have placeholders:
+//
+//   - $, $$@-3~2|roman: the number in the innermost multiplication
+//   - ${$*10}: an expression of the number
+//   - ${name}, ${name:fallback}: a variable of WithData
+//   - \$: a literal "$"
+//
+// Since "${" starts a variable, li.item-${ITEM}*3 is an undefined variable ITEM
+// (it was the number followed by the text {ITEM} before variables).
+// Write li.item-$ {ITEM}*3 for the number and the text, or li.item-\${ITEM}*3 for a literal "${".
+//
 // # Limitations
 //
 //   - No implicit tag names (`ul>.cls` causes an error)
-//   - Generates always empty-element tags (yes: <a />, no: <a></a>)
+//   - Generates always empty-element tags in XML mode (yes: <a />, no: <a></a>)
 //   - (internal) each TEXT {...}, QTEXT "..." is a token, unlike attr-list [, ..., ]
 package ennet
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"reflect"
 	"slices"
 	"strconv"
 	"strings"
@@ -16,13 +34,28 @@ import (
 )
 
 // Expand expands Emmet abbreviation in string s.
-func Expand(s string) (string, error) {
+func Expand(s string, opts ...Option) (string, error) {
+	o := &noOptions
+	if len(opts) > 0 {
+		o = &options{}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+
 	b := expandBufPool.Get().(*bytes.Buffer)
 	b.Reset()
 	b.WriteString(s)
 
 	nodeBuilder := NewNodeBuilder(&nodePool)
-	err := Parse(b.Bytes(), &nodeBuilder)
+	nodeBuilder.Merges = o.merges
+	nodeBuilder.Defaults = o.defaultAttributes()
+	if len(o.namespaces) > 0 {
+		for _, prefix := range slices.Sorted(maps.Keys(o.namespaces)) {
+			nodeBuilder.DeclareNamespace(prefix, o.namespaces[prefix])
+		}
+	}
+	err := parse(b.Bytes(), &nodeBuilder, o.macros, o.fragments)
 	if err != nil {
 		expandBufPool.Put(b)
 		return "", err
@@ -30,16 +63,42 @@ func Expand(s string) (string, error) {
 
 	resBuf := expandBufPool.Get().(*bytes.Buffer)
 	resBuf.Reset()
-	expand(nodeBuilder.Root, resBuf)
-	result := resBuf.String()
+	r := renderer{w: resBuf, opts: o, block: o.indent != ""}
+	if o.namespaces != nil {
+		r.declareNamespaces(nodeBuilder.Root, nil)
+	}
+	if o.hasProlog() {
+		r.writeProlog()
+	}
+	r.expand(nodeBuilder.Root)
+	if r.err == nil && o.hasProlog() && r.roots == 0 {
+		r.err = errors.New("a root element is required with a prolog")
+	}
+	if r.err == nil && o.maxOutput > 0 && resBuf.Len() > o.maxOutput {
+		r.err = ErrOutputLimit
+	}
+	result := ""
+	if r.err == nil {
+		result = resBuf.String()
+	}
 
 	gcNodes(&nodeBuilder, &nodePool, nodeBuilder.Root)
 
 	expandBufPool.Put(resBuf)
 	expandBufPool.Put(b)
-	return result, nil
+	return result, r.err
 }
 
+// ErrMulLimit is reported (in a ParseError) when a multiplication exceeds WithMaxMul.
+var ErrMulLimit = errors.New("multiplication exceeds the limit")
+
+// ErrLoremLimit is reported (in a ParseError) when the words of dummy text (loremN),
+// multiplied by the enclosing multiplications, exceed WithMaxMul.
+var ErrLoremLimit = errors.New("words of dummy text exceed the limit")
+
+// ErrOutputLimit is reported when the output exceeds WithMaxOutput.
+var ErrOutputLimit = errors.New("output exceeds the limit")
+
 func gcNodes(nb *NodeBuilder, pool *sync.Pool, n *Node) {
 	if pool == nil {
 		return
@@ -53,99 +112,347 @@ func gcNodes(nb *NodeBuilder, pool *sync.Pool, n *Node) {
 	pool.Put(n)
 }
 
-func expand(n *Node, w *bytes.Buffer) {
+// SourceMapping maps a range of the expanded output to the abbreviation.
+//
+// OutStart and OutEnd are byte offsets of the output (out[OutStart:OutEnd]).
+// Pos and End are 1-based like Token.Pos, and End is exclusive.
+// Each copy of a multiplied node maps to the same Pos and End.
+type SourceMapping struct {
+	OutStart, OutEnd int
+	Pos, End         int
+}
+
+type renderer struct {
+	w    *bytes.Buffer
+	opts *options
+
+	// iterations of the enclosing multiplications, innermost last
+	iters []iteration
+	// items of the enclosing multiplications over data, innermost last
+	items []reflect.Value
+
+	// indentation
+	depth int
+	block bool // children of the current element are put on their own lines
+
+	// attributes being written, in the output order
+	attrs []Attribute
+
+	// root elements, with a prolog
+	roots int
+
+	// dummy text
+	rng          *rand.Rand
+	loremStarted bool
+
+	err error
+}
+
+type iteration struct {
+	index, count int
+}
+
+func (r *renderer) expand(n *Node) {
+	if r.err != nil {
+		return
+	}
+	if max := r.opts.maxOutput; max > 0 && r.w.Len() > max {
+		r.err = ErrOutputLimit
+		return
+	}
+
+	if n.MulData != "" {
+		r.expandData(n)
+		return
+	}
 	if n.Mul <= 0 {
-		expandNode(n, w)
+		r.breakLine(n)
+		r.expandMapped(n)
+		return
+	}
+
+	if !r.checkMul(n, n.Mul, ErrMulLimit) {
+		return
+	}
+
+	for i := range n.Mul {
+		if r.err != nil {
+			return
+		}
+		r.iters = append(r.iters, iteration{index: i, count: n.Mul})
+		r.breakLine(n)
+		r.expandMapped(n)
+		r.iters = r.iters[:len(r.iters)-1]
+	}
+}
+
+// checkMul fails with err if count copies of n in the enclosing multiplications exceed WithMaxMul.
+func (r *renderer) checkMul(n *Node, count int, err error) bool {
+	max := r.opts.maxMul
+	if max <= 0 {
+		return true
+	}
+
+	total := count
+	for i := len(r.iters) - 1; i >= 0 && total <= max; i-- {
+		total *= r.iters[i].count
+	}
+	if total > max {
+		r.err = &ParseError{Pos: n.Pos, End: n.End, Err: err}
+		return false
+	}
+	return true
+}
+
+// breakLine starts a new indented line for n if needed.
+func (r *renderer) breakLine(n *Node) {
+	if !r.block || n.Type == Root || n.Type == WIP || n.Type == Group || r.w.Len() == 0 {
+		return
+	}
+	r.w.WriteByte('\n')
+	for range r.depth {
+		r.w.WriteString(r.opts.indent)
+	}
+}
+
+func (r *renderer) expandMapped(n *Node) {
+	sm := r.opts.sourceMap
+	if sm == nil || n.Pos == 0 {
+		r.expandNode(n)
 		return
 	}
 
-	buf := expandBufPool.Get().(*bytes.Buffer)
-	buf.Reset()
-	expandNode(n, buf)
-	writeMul(w, buf.Bytes(), n.Mul)
-	expandBufPool.Put(buf)
+	// reserve the slot to keep mappings in the order of OutStart
+	idx := len(*sm)
+	*sm = append(*sm, SourceMapping{OutStart: r.w.Len(), Pos: n.Pos, End: n.End})
+	r.expandNode(n)
+	(*sm)[idx].OutEnd = r.w.Len()
 }
 
-func expandNode(n *Node, w *bytes.Buffer) {
+func (r *renderer) expandNode(n *Node) {
+	if r.depth == 0 && r.opts.hasProlog() {
+		if r.countRoot(n); r.err != nil {
+			return
+		}
+	}
+
+	w := r.w
 	switch n.Type {
 	case Text:
-		w.WriteString(n.Data)
+		r.writeTemplate(n, n.Data, escText)
+	case Comment:
+		w.WriteString("<!--")
+		r.writeMarkup(n)
+		w.WriteString("-->")
+	case CData:
+		w.WriteString("<![CDATA[")
+		r.writeMarkup(n)
+		w.WriteString("]]>")
+	case ProcInst:
+		w.WriteString("<?")
+		r.writeMarkup(n)
+		w.WriteString("?>")
 	case Root, Group:
 		curr := n.FirstChild
 		for curr != nil {
-			expand(curr, w)
+			r.expand(curr)
 			curr = curr.NextSibling
 		}
 	case Element:
+		if count, ok := r.loremCount(n); ok {
+			r.lorem(n, count)
+			return
+		}
+
 		w.WriteString("<")
-		w.WriteString(n.Data)
+		start := w.Len()
+		r.writeTemplate(n, n.Data, escNone)
+		r.checkName(n, n.Data, start)
 		if len(n.Attributes) > 0 {
-			slices.SortFunc(n.Attributes, func(a, b Attribute) int {
-				return strings.Compare(a.Name, b.Name)
-			})
-			for _, attr := range n.Attributes {
+			for _, attr := range r.orderedAttributes(n) {
 				w.WriteString(" ")
-				w.WriteString(attr.Name)
-				w.WriteString(`="`)
-				// manual escape
-				val := attr.Value
-				last := 0
-				for i := 0; i < len(val); i++ {
-					if val[i] == '"' {
-						w.WriteString(val[last:i])
-						w.WriteString(`\"`)
-						last = i + 1
+				start := w.Len()
+				r.writeTemplate(n, attr.Name, escNone)
+				r.checkName(n, attr.Name, start)
+
+				value := attr.Value
+				if value == "" && r.isBooleanAttribute(attr.Name) {
+					if r.opts.mode == HTML {
+						continue // <input disabled>
 					}
+					value = attr.Name // <input disabled="disabled" />
 				}
-				w.WriteString(val[last:])
+				w.WriteString(`="`)
+				r.writeTemplate(n, value, escAttr)
 				w.WriteString(`"`)
 			}
 		}
 
 		if n.FirstChild == nil {
-			w.WriteString(" />")
-		} else {
-			w.WriteString(">")
-			curr := n.FirstChild
-			for curr != nil {
-				expand(curr, w)
-				curr = curr.NextSibling
+			switch {
+			case r.opts.mode == XML:
+				w.WriteString(" />")
+				return
+			case isVoidElement(n.Data):
+				if r.opts.mode == XHTML {
+					w.WriteString(" />")
+				} else {
+					w.WriteString(">")
+				}
+				return
 			}
-			w.WriteString("</")
-			w.WriteString(n.Data)
-			w.WriteString(">")
 		}
+
+		w.WriteString(">")
+
+		block, depth := r.block, r.depth
+		r.block = r.opts.indent != "" && r.hasElementChild(n)
+		r.depth++
+		curr := n.FirstChild
+		for curr != nil {
+			r.expand(curr)
+			curr = curr.NextSibling
+		}
+		r.depth--
+		if r.block {
+			r.w.WriteByte('\n')
+			for range r.depth {
+				r.w.WriteString(r.opts.indent)
+			}
+		}
+		r.block, r.depth = block, depth
+
+		w.WriteString("</")
+		r.writeTemplate(n, n.Data, escNone)
+		w.WriteString(">")
 	}
 }
 
-type segment struct {
-	literal []byte
-	isPH    bool
-	pad     int
-	base    int
-	minus   bool
+// writeMarkup writes the content of a comment, a CDATA section or a processing instruction,
+// breaking the sequences that would end it.
+func (r *renderer) writeMarkup(n *Node) {
+	start := r.w.Len()
+	r.writeTemplate(n, n.Data, escNone)
+
+	s := string(r.w.Bytes()[start:])
+	switch n.Type {
+	case Comment:
+		if !strings.Contains(s, "--") && !strings.HasSuffix(s, "-") {
+			return
+		}
+		for strings.Contains(s, "--") {
+			s = strings.ReplaceAll(s, "--", "- -")
+		}
+		if strings.HasSuffix(s, "-") {
+			s += " "
+		}
+	case CData:
+		if !strings.Contains(s, "]]>") {
+			return
+		}
+		s = strings.ReplaceAll(s, "]]>", "]]]]><![CDATA[>")
+	case ProcInst:
+		if !strings.Contains(s, "?>") {
+			return
+		}
+		s = strings.ReplaceAll(s, "?>", "? >")
+	}
+	r.w.Truncate(start)
+	r.w.WriteString(s)
 }
 
-func writeMul(w *bytes.Buffer, templ []byte, mul int) {
+func (r *renderer) hasElementChild(n *Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == Element {
+			if _, ok := r.loremCount(c); !ok {
+				return true
+			}
+		} else if c.Type == Group && r.hasElementChild(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func isVoidElement(name string) bool {
+	switch name {
+	case "area", "base", "br", "col", "embed", "hr", "img", "input",
+		"link", "meta", "param", "source", "track", "wbr":
+		return true
+	default:
+		return false
+	}
+}
+
+// isBooleanAttribute reports whether name is a boolean attribute of HTML or of WithBooleanAttributes.
+func (r *renderer) isBooleanAttribute(name string) bool {
+	switch strings.ToLower(name) {
+	case "allowfullscreen", "async", "autofocus", "autoplay", "checked", "controls",
+		"default", "defer", "disabled", "formnovalidate", "hidden", "inert", "ismap",
+		"itemscope", "loop", "multiple", "muted", "nomodule", "novalidate", "open",
+		"playsinline", "readonly", "required", "reversed", "selected":
+		return true
+	default:
+		return slices.Contains(r.opts.boolAttrs, name)
+	}
+}
+
+type escapeContext uint8
+
+const (
+	escNone escapeContext = iota
+	escText
+	escAttr
+)
+
+// writeTemplate writes s of n replacing numbering placeholders ($, $$@-3, ...)
+// and expressions (${$*10}) by the innermost iteration, and variables (${name}) by the data.
+// \$ is a literal "$".
+func (r *renderer) writeTemplate(n *Node, s string, ctx escapeContext) {
+	if strings.IndexByte(s, '$') == -1 {
+		r.writeString(s, ctx)
+		return
+	}
+
 	pSegs := segmentSlicePool.Get().(*[]segment)
-	segments := parseTemplateInto(templ, (*pSegs)[:0])
+	segments := parseTemplateInto(s, (*pSegs)[:0])
 
 	var numBuf [32]byte
-	for i := range mul {
-		for _, seg := range segments {
-			if !seg.isPH {
-				w.Write(seg.literal)
-			} else {
-				val := seg.base + i
-				if seg.minus {
-					val = seg.base + mul - 1 - i
+	for _, seg := range segments {
+		switch {
+		case seg.isEscape:
+			r.writeString(seg.literal[1:], ctx)
+
+		case seg.isVar:
+			name, fallback, hasFallback := parseVariable(seg.literal)
+			val, found := r.lookup(name)
+			if !found {
+				if !hasFallback {
+					if r.err == nil {
+						r.err = &ParseError{Pos: n.Pos, End: n.End, Err: fmt.Errorf("%w %s", ErrUndefinedVariable, name)}
+					}
+					continue
 				}
-				b := strconv.AppendInt(numBuf[:0], int64(val), 10)
-				for j := 0; j < seg.pad-len(b); j++ {
-					w.WriteString("0")
+				val = fallback
+			}
+			r.writeData(val, ctx)
+
+		case seg.isPH && len(r.iters) > 0:
+			r.writeNumber(n, numBuf[:0], seg.number(r.iters[len(r.iters)-1]), seg.pad, seg.format)
+
+		case seg.isExpr && len(r.iters) > 0:
+			expr, format, _ := strings.Cut(seg.literal[2:len(seg.literal)-1], "|")
+			val, pad, err := evalExpression(expr, r.iters[len(r.iters)-1])
+			if err != nil {
+				if r.err == nil {
+					r.err = &ParseError{Pos: n.Pos, End: n.End, Err: err}
 				}
-				w.Write(b)
+				continue
 			}
+			r.writeNumber(n, numBuf[:0], val, pad, format)
+
+		default:
+			r.writeString(seg.literal, ctx)
 		}
 	}
 
@@ -153,39 +460,131 @@ func writeMul(w *bytes.Buffer, templ []byte, mul int) {
 	segmentSlicePool.Put(pSegs)
 }
 
-func parseTemplateInto(templ []byte, segments []segment) []segment {
+// writeNumber writes val of n in format, padded with zeros to pad digits, using buf.
+func (r *renderer) writeNumber(n *Node, buf []byte, val, pad int, format string) {
+	if format != "" {
+		f, found := lookupCounterFormat(format)
+		if !found {
+			if r.err == nil {
+				r.err = &ParseError{Pos: n.Pos, End: n.End, Err: errors.New("unknown counter format " + format)}
+			}
+			return
+		}
+		r.w.WriteString(f(val, pad))
+		return
+	}
+
+	b := strconv.AppendInt(buf, int64(val), 10)
+	for j := 0; j < pad-len(b); j++ {
+		r.w.WriteString("0")
+	}
+	r.w.Write(b)
+}
+
+func (r *renderer) writeString(s string, ctx escapeContext) {
+	if ctx == escNone {
+		r.w.WriteString(s)
+		return
+	}
+
+	// manual escape
+	last := 0
+	for i := 0; i < len(s); i++ {
+		esc := r.escape(s[i], ctx)
+		if esc == "" {
+			continue
+		}
+		r.w.WriteString(s[last:i])
+		r.w.WriteString(esc)
+		last = i + 1
+	}
+	r.w.WriteString(s[last:])
+}
+
+// writeData writes a value of the data, always escaped.
+func (r *renderer) writeData(s string, ctx escapeContext) {
+	if ctx == escNone {
+		ctx = escAttr
+	}
+	last := 0
+	for i := 0; i < len(s); i++ {
+		esc := entity(s[i], ctx)
+		if esc == "" {
+			continue
+		}
+		r.w.WriteString(s[last:i])
+		r.w.WriteString(esc)
+		last = i + 1
+	}
+	r.w.WriteString(s[last:])
+}
+
+func (r *renderer) escape(b byte, ctx escapeContext) string {
+	if !r.opts.escape {
+		if b == '"' && ctx == escAttr {
+			return `\"`
+		}
+		return ""
+	}
+	return entity(b, ctx)
+}
+
+func entity(b byte, ctx escapeContext) string {
+	switch b {
+	case '&':
+		return "&amp;"
+	case '<':
+		return "&lt;"
+	case '>':
+		return "&gt;"
+	case '"':
+		if ctx == escAttr {
+			return "&quot;"
+		}
+	}
+	return ""
+}
+
+type segment struct {
+	literal  string // the literal, or the source of the placeholder
+	isEscape bool   // \$
+	isPH     bool
+	isVar    bool // ${name}
+	isExpr   bool // ${$*10}
+	pad      int
+	base     int
+	minus    bool
+	step     int
+	format   string // a CounterFormat
+}
+
+func parseTemplateInto(templ string, segments []segment) []segment {
 	last := 0
 	for i := 0; i < len(templ); i++ {
-		if templ[i] == '$' {
+		if templ[i] == '\\' && i+1 < len(templ) && templ[i+1] == '$' {
 			if i > last {
 				segments = append(segments, segment{literal: templ[last:i]})
 			}
-			pad := 1
+			segments = append(segments, segment{literal: templ[i : i+2], isEscape: true})
 			i++
-			for i < len(templ) && templ[i] == '$' {
-				pad++
-				i++
+			last = i + 1
+			continue
+		}
+		if templ[i] == '$' {
+			if i > last {
+				segments = append(segments, segment{literal: templ[last:i]})
 			}
-			minus := false
-			base := 1
-			if i < len(templ) && templ[i] == '@' {
-				i++
-				if i < len(templ) && templ[i] == '-' {
-					minus = true
-					i++
-				}
-				b := 0
-				foundBase := false
-				for i < len(templ) && templ[i] >= '0' && templ[i] <= '9' {
-					b = b*10 + int(templ[i]-'0')
-					i++
-					foundBase = true
-				}
-				if foundBase && b > 0 {
-					base = b
-				}
+
+			var seg segment
+			if n := variableLen(templ[i:]); n > 0 {
+				seg = segment{literal: templ[i : i+n], isVar: true}
+			} else if n := expressionLen(templ[i:]); n > 0 {
+				seg = segment{literal: templ[i : i+n], isExpr: true}
+			} else {
+				seg, _ = parseNumbering(templ[i:]) // checked by the parser
 			}
-			segments = append(segments, segment{isPH: true, pad: pad, base: base, minus: minus})
+			segments = append(segments, seg)
+			i += len(seg.literal)
 			last = i
 			i-- // back up for the outer loop's i++
 		}
@@ -196,6 +595,117 @@ func parseTemplateInto(templ []byte, segments []segment) []segment {
 	return segments
 }
 
+// parseNumbering parses the numbering placeholder ($$@-10~5|roman) at the start of s.
+//
+//	numbering = "$", { "$" }, [ "@", [ "-" ], [ NUMBER ] ], [ "~", NUMBER ], [ "|", FORMAT ];
+//
+// Numbers are up to 2147483647, so that numbers of multiplications do not overflow.
+// On an invalid form, it returns an error with the segment parsed so far.
+func parseNumbering(s string) (segment, error) {
+	seg := segment{isPH: true, base: 1, step: 1}
+
+	i := 0
+	for i < len(s) && s[i] == '$' {
+		seg.pad++
+		i++
+	}
+	if i < len(s) && s[i] == '@' {
+		i++
+		if i < len(s) && s[i] == '-' {
+			seg.minus = true
+			i++
+		}
+		if n := digitsLen(s[i:]); n > 0 {
+			base, err := strconv.ParseInt(s[i:i+n], 10, 32)
+			i += n
+			if err != nil {
+				seg.literal = s[:i]
+				return seg, errors.New("the number following $@ is too large")
+			}
+			seg.base = int(base)
+		} else if !seg.minus {
+			seg.literal = s[:i]
+			return seg, errors.New("a number following $@ is required")
+		}
+	}
+	if i < len(s) && s[i] == '~' {
+		i++
+		n := digitsLen(s[i:])
+		if n == 0 {
+			seg.literal = s[:i]
+			return seg, errors.New("a number following ~ is required")
+		}
+		step, err := strconv.ParseInt(s[i:i+n], 10, 32)
+		i += n
+		if err != nil {
+			seg.literal = s[:i]
+			return seg, errors.New("the number following ~ is too large")
+		}
+		seg.step = int(step)
+		if seg.step == 0 {
+			seg.literal = s[:i]
+			return seg, errors.New("step must not be 0")
+		}
+	}
+	if i+1 < len(s) && s[i] == '|' {
+		// an unknown format is reported when numbered, since $|name out of multiplications is a literal
+		if n := counterFormatLen(s[i+1:]); n > 0 {
+			seg.format = s[i+1 : i+1+n]
+			i += 1 + n
+		}
+	}
+	seg.literal = s[:i]
+	return seg, nil
+}
+
+func digitsLen(s string) int {
+	i := 0
+	for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// number returns the number of the numbering placeholder seg in iter.
+func (seg segment) number(iter iteration) int {
+	if seg.minus {
+		return seg.base + (iter.count-1-iter.index)*seg.step
+	}
+	return seg.base + iter.index*seg.step
+}
+
+// checkTemplate returns the range and the error of the first invalid placeholder in s.
+func checkTemplate(s string) (start, end int, err error) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && s[i+1] == '$' {
+			i++
+			continue
+		}
+		if s[i] != '$' {
+			continue
+		}
+
+		if n := variableLen(s[i:]); n > 0 {
+			i += n - 1
+			continue
+		}
+		if n := expressionLen(s[i:]); n > 0 {
+			if err := checkExpression(s[i+2 : i+n-1]); err != nil {
+				return i, i + n, err
+			}
+			i += n - 1
+			continue
+		}
+
+		seg, err := parseNumbering(s[i:])
+		if err != nil {
+			return i, i + len(seg.literal), err
+		}
+		i += len(seg.literal) - 1
+	}
+	return 0, 0, nil
+}
+
 var expandBufPool = sync.Pool{
 	New: func() any {
 		return &bytes.Buffer{}
@@ -2,6 +2,7 @@ package ennet_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/shu-go/ennet"
@@ -36,11 +37,11 @@ func TestEnnet(t *testing.T) {
 
 		s, err = ennet.Expand(`a#id1.classA[attr1 attr2=2]`)
 		gotwant.TestError(t, err, nil)
-		gotwant.Test(t, s, `<a attr1="" attr2="2" class="classA" id="id1" />`)
+		gotwant.Test(t, s, `<a id="id1" class="classA" attr1="" attr2="2" />`)
 
 		s, err = ennet.Expand(`a#id1.classA[attr1 attr2=2]{text desu}`)
 		gotwant.TestError(t, err, nil)
-		gotwant.Test(t, s, `<a attr1="" attr2="2" class="classA" id="id1">text desu</a>`)
+		gotwant.Test(t, s, `<a id="id1" class="classA" attr1="" attr2="2">text desu</a>`)
 	})
 
 	t.Run("Elements", func(t *testing.T) {
@@ -142,13 +143,13 @@ func TestEnnetDocumentation(t *testing.T) {
 	t.Run(`div#header+div.page+div#footer.class1.class2.class3`, func(t *testing.T) {
 		s, err := ennet.Expand(`div#header+div.page+div#footer.class1.class2.class3`)
 		gotwant.TestError(t, err, nil)
-		gotwant.Test(t, s, `<div id="header" /><div class="page" /><div class="class1 class2 class3" id="footer" />`)
+		gotwant.Test(t, s, `<div id="header" /><div class="page" /><div id="footer" class="class1 class2 class3" />`)
 	})
 
 	t.Run(`td[title="Hello world!" colspan=3]`, func(t *testing.T) {
 		s, err := ennet.Expand(`td[title="Hello world!" colspan=3]`)
 		gotwant.TestError(t, err, nil)
-		gotwant.Test(t, s, `<td colspan="3" title="Hello world!" />`)
+		gotwant.Test(t, s, `<td title="Hello world!" colspan="3" />`)
 	})
 
 	t.Run(`ul>li.item$*5`, func(t *testing.T) {
@@ -220,3 +221,588 @@ func BenchmarkEnnet(b *testing.B) {
 		}
 	})
 }
+
+func TestSourceMap(t *testing.T) {
+	t.Run("Element", func(t *testing.T) {
+		abbr := `a#i[x=1]>{t}+b`
+		var sm []ennet.SourceMapping
+		s, err := ennet.Expand(abbr, ennet.WithSourceMap(&sm))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a id="i" x="1">t<b /></a>`)
+
+		gotwant.Test(t, len(sm), 3)
+		gotwant.Test(t, s[sm[0].OutStart:sm[0].OutEnd], s)
+		gotwant.Test(t, abbr[sm[0].Pos-1:sm[0].End-1], `a#i[x=1]`)
+		gotwant.Test(t, s[sm[1].OutStart:sm[1].OutEnd], `t`)
+		gotwant.Test(t, abbr[sm[1].Pos-1:sm[1].End-1], `{t}`)
+		gotwant.Test(t, s[sm[2].OutStart:sm[2].OutEnd], `<b />`)
+		gotwant.Test(t, abbr[sm[2].Pos-1:sm[2].End-1], `b`)
+	})
+
+	t.Run("Mul", func(t *testing.T) {
+		abbr := `ul>(li.item$)*2`
+		var sm []ennet.SourceMapping
+		s, err := ennet.Expand(abbr, ennet.WithSourceMap(&sm))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<ul><li class="item1" /><li class="item2" /></ul>`)
+
+		var got []string
+		for _, m := range sm {
+			got = append(got, s[m.OutStart:m.OutEnd]+" <- "+abbr[m.Pos-1:m.End-1])
+		}
+		gotwant.Test(t, got, []string{
+			`<ul><li class="item1" /><li class="item2" /></ul> <- ul`,
+			`<li class="item1" /> <- (li.item$)*2`,
+			`<li class="item1" /> <- li.item$`,
+			`<li class="item2" /> <- (li.item$)*2`,
+			`<li class="item2" /> <- li.item$`,
+		})
+	})
+}
+
+func TestOptions(t *testing.T) {
+	t.Run("Mode", func(t *testing.T) {
+		s, err := ennet.Expand(`p>br+a`, ennet.WithMode(ennet.XML))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p><br /><a /></p>`)
+
+		s, err = ennet.Expand(`p>br+a`, ennet.WithMode(ennet.HTML))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p><br><a href=""></a></p>`)
+
+		s, err = ennet.Expand(`p>br+a`, ennet.WithMode(ennet.XHTML))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p><br /><a href=""></a></p>`)
+
+		m, err := ennet.ParseMode("HTML")
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, m, ennet.HTML)
+		_, err = ennet.ParseMode("svg")
+		gotwant.TestError(t, err, "unknown mode")
+	})
+
+	t.Run("Indent", func(t *testing.T) {
+		s, err := ennet.Expand(`ul>li.item$*2>a{$}^^p`, ennet.WithIndent("  "))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<ul>
+  <li class="item1">
+    <a>1</a>
+  </li>
+  <li class="item2">
+    <a>2</a>
+  </li>
+</ul>
+<p />`)
+	})
+
+	t.Run("Escape", func(t *testing.T) {
+		s, err := ennet.Expand(`a[title='"<&>"']{"<&>"}`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a title="\"<&>\"">"<&>"</a>`)
+
+		s, err = ennet.Expand(`a[title='"<&>"']{"<&>"}`, ennet.WithEscape(true))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a title="&quot;&lt;&amp;&gt;&quot;">"&lt;&amp;&gt;"</a>`)
+	})
+}
+
+func TestLimits(t *testing.T) {
+	s, err := ennet.Expand(`ul>li*3`, ennet.WithMaxMul(3))
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, s, `<ul><li /><li /><li /></ul>`)
+
+	_, err = ennet.Expand(`ul>li*4`, ennet.WithMaxMul(3))
+	gotwant.TestError(t, err, ennet.ErrMulLimit)
+	perr := err.(*ennet.ParseError)
+	gotwant.Test(t, perr.Pos, 4)
+	gotwant.Test(t, perr.End, 8)
+
+	_, err = ennet.Expand(`ul>li*3`, ennet.WithMaxOutput(27))
+	gotwant.TestError(t, err, nil)
+
+	_, err = ennet.Expand(`ul>li*3`, ennet.WithMaxOutput(26))
+	gotwant.TestError(t, err, ennet.ErrOutputLimit)
+
+	_, err = ennet.Expand(`(ul>li*100)*100`, ennet.WithMaxMul(10000), ennet.WithMaxOutput(1000))
+	gotwant.TestError(t, err, ennet.ErrOutputLimit)
+
+	s, err = ennet.Expand(`(a>b*2)*3`, ennet.WithMaxMul(6))
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, s, `<a><b /><b /></a><a><b /><b /></a><a><b /><b /></a>`)
+
+	_, err = ennet.Expand(`(a>b*3)*3`, ennet.WithMaxMul(6))
+	gotwant.TestError(t, err, ennet.ErrMulLimit)
+	perr = err.(*ennet.ParseError)
+	gotwant.Test(t, [2]int{perr.Pos, perr.End}, [2]int{4, 7})
+
+	_, err = ennet.Expand(`(({}*1000)*1000)*100`, ennet.WithMaxMul(1000))
+	gotwant.TestError(t, err, ennet.ErrMulLimit)
+}
+
+func TestLorem(t *testing.T) {
+	t.Run("Words", func(t *testing.T) {
+		s, err := ennet.Expand(`p>lorem5`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p>Lorem ipsum dolor sit amet.</p>`)
+
+		s, err = ennet.Expand(`lorem`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, len(strings.Fields(s)), 30)
+	})
+
+	t.Run("Mul", func(t *testing.T) {
+		s, err := ennet.Expand(`p*3>ipsum5`)
+		gotwant.TestError(t, err, nil)
+		ps := strings.Split(strings.TrimSuffix(strings.TrimPrefix(s, "<p>"), "</p>"), "</p><p>")
+		gotwant.Test(t, len(ps), 3)
+		if ps[0] == ps[1] || ps[1] == ps[2] {
+			t.Errorf("copies must differ: %q", ps)
+		}
+	})
+
+	t.Run("Seed", func(t *testing.T) {
+		s1, _ := ennet.Expand(`p*3>lorem20`, ennet.WithLorem(ennet.LatinLorem(), 1))
+		s2, _ := ennet.Expand(`p*3>lorem20`, ennet.WithLorem(ennet.LatinLorem(), 1))
+		s3, _ := ennet.Expand(`p*3>lorem20`, ennet.WithLorem(ennet.LatinLorem(), 2))
+		gotwant.Test(t, s1, s2)
+		if s1 == s3 {
+			t.Errorf("seeds must give different text: %q", s1)
+		}
+	})
+
+	t.Run("Corpus", func(t *testing.T) {
+		s, err := ennet.Expand(`p>lorem5`, ennet.WithLorem(ennet.JapaneseLorem(), 0))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p>吾輩は猫である。名前はまだ無い。</p>`)
+
+		s, err = ennet.Expand(`p>lorem`, ennet.WithLorem(ennet.LoremCorpus{}, 0))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p><lorem /></p>`)
+	})
+
+	t.Run("Capitalize", func(t *testing.T) {
+		corpus := ennet.LoremCorpus{Words: []string{"élan"}, Separator: " ", Terminator: ".", Capitalize: true}
+		s, err := ennet.Expand(`p>lorem2`, ennet.WithLorem(corpus, 0))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p>Élan élan.</p>`)
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		_, err := ennet.Expand(`lorem999999999`, ennet.WithMaxOutput(1<<20))
+		gotwant.TestError(t, err, ennet.ErrOutputLimit)
+
+		_, err = ennet.Expand(`lorem100000`, ennet.WithMaxOutput(1<<10))
+		gotwant.TestError(t, err, ennet.ErrOutputLimit)
+
+		_, err = ennet.Expand(`p*10>lorem200`, ennet.WithMaxMul(1000))
+		gotwant.TestError(t, err, ennet.ErrLoremLimit)
+
+		_, err = ennet.Expand(`lorem999999999`, ennet.WithMaxMul(1000))
+		gotwant.TestError(t, err, "words of dummy text exceed the limit")
+	})
+
+	t.Run("Copy", func(t *testing.T) {
+		corpus := ennet.LatinLorem()
+		corpus.Start[0] = "x"
+		opt := ennet.WithLorem(corpus, 0)
+		corpus.Start[1] = "y"
+
+		s, err := ennet.Expand(`p>lorem2`, opt)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p>X ipsum.</p>`)
+
+		s, err = ennet.Expand(`p>lorem2`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p>Lorem ipsum.</p>`)
+	})
+
+	t.Run("NotLorem", func(t *testing.T) {
+		s, err := ennet.Expand(`lorem[x]+lorem>a+loremX`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<lorem x="" /><lorem><a /><loremX /></lorem>`)
+	})
+}
+
+func TestData(t *testing.T) {
+	type Link struct {
+		URL   string
+		Label string
+		Count int
+		Meta  map[string]any
+	}
+	link := &Link{URL: `/a?b="1"&c=2`, Label: "<b>$1 }}", Count: 3, Meta: map[string]any{"rel": "next", "none": nil}}
+
+	t.Run("Map", func(t *testing.T) {
+		s, err := ennet.Expand(`a[href=${url}]{${label}}`, ennet.WithData(map[string]string{"url": "/x", "label": "X"}))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a href="/x">X</a>`)
+	})
+
+	t.Run("Struct", func(t *testing.T) {
+		s, err := ennet.Expand(`a[href=${URL} rel=${Meta.rel}]{${Label} (${Count})}`, ennet.WithData(link))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a href="/a?b=&quot;1&quot;&amp;c=2" rel="next">&lt;b&gt;$1 }} (3)</a>`)
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		s, err := ennet.Expand(`${Tag:p}.${Meta.none:empty}{${Nothing:n/a}}`, ennet.WithData(link))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p class="empty">n/a</p>`)
+
+		s, err = ennet.Expand(`p{${x:}}`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p></p>`)
+	})
+
+	t.Run("Mul", func(t *testing.T) {
+		s, err := ennet.Expand(`li.${Meta.rel}${${Count}-$}*2`, ennet.WithData(link))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<li class="next1">3-1</li><li class="next2">3-2</li>`)
+	})
+
+	t.Run("Undefined", func(t *testing.T) {
+		_, err := ennet.Expand(`p>a{${Nothing}}`, ennet.WithData(link))
+		gotwant.TestError(t, err, ennet.ErrUndefinedVariable)
+		gotwant.TestError(t, err, "undefined variable Nothing")
+		perr := err.(*ennet.ParseError)
+		gotwant.Test(t, perr.Pos, 4)
+		gotwant.Test(t, perr.End, 16)
+
+		_, err = ennet.Expand(`p{${URL.x}}`, ennet.WithData(link))
+		gotwant.TestError(t, err, ennet.ErrUndefinedVariable)
+	})
+
+	t.Run("Names", func(t *testing.T) {
+		data := map[string]string{"tag": "section", "attr": "data-id", "bad": "x onload=y", "quote": `a"b`, "empty": ""}
+
+		s, err := ennet.Expand(`${tag}[${attr}=1]`, ennet.WithData(data))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<section data-id="1" />`)
+
+		for _, abbr := range []string{`${bad}`, `a[${bad}]`, `a[${quote}=1]`, `${empty}`, `a[x${bad}]`} {
+			_, err := ennet.Expand(abbr, ennet.WithData(data))
+			gotwant.TestError(t, err, "invalid name", gotwant.Desc(abbr))
+		}
+	})
+
+	t.Run("NumberingBeforeText", func(t *testing.T) {
+		_, err := ennet.Expand(`ul>li.item-${ITEM}*3`)
+		gotwant.TestError(t, err, "undefined variable ITEM at 4")
+
+		s, err := ennet.Expand(`ul>li.item-$ {ITEM}*2`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<ul><li class="item-1">ITEM</li><li class="item-2">ITEM</li></ul>`)
+
+		s, err = ennet.Expand(`ul>li.item-\${ITEM}*2`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<ul><li class="item-${ITEM}" /><li class="item-${ITEM}" /></ul>`)
+	})
+}
+
+func TestDataMul(t *testing.T) {
+	type Link struct {
+		Name string
+		Tags []string
+	}
+	data := map[string]any{
+		"title": "links",
+		"links": []Link{{Name: "a", Tags: []string{"x", "y"}}, {Name: "<b>"}},
+	}
+
+	t.Run("Items", func(t *testing.T) {
+		s, err := ennet.Expand(`ul>li.item$*@links{${.Name} of ${title}}`, ennet.WithData(data))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<ul><li class="item1">a of links</li><li class="item2">&lt;b&gt; of links</li></ul>`)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		s, err := ennet.Expand(`(dt{${.Name}}+dd{${.}:$/${Name}}*@.Tags)*@links`, ennet.WithData(data))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<dt>a</dt><dd>x:1/a</dd><dd>y:2/a</dd><dt>&lt;b&gt;</dt>`)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := ennet.Expand(`li*@nothing`, ennet.WithData(data))
+		gotwant.TestError(t, err, ennet.ErrUndefinedVariable)
+
+		_, err = ennet.Expand(`li*@title`, ennet.WithData(data))
+		gotwant.TestError(t, err, "title is not a slice")
+
+		_, err = ennet.Expand(`li*@links`, ennet.WithData(data), ennet.WithMaxMul(1))
+		gotwant.TestError(t, err, ennet.ErrMulLimit)
+	})
+}
+
+func TestExpression(t *testing.T) {
+	s, err := ennet.Expand(`li.w${$*10}[data-index=${$-1}]{${$%2}}*3`)
+	gotwant.TestError(t, err, nil)
+	gotwant.Test(t, s, `<li class="w10" data-index="0">1</li><li class="w20" data-index="1">0</li><li class="w30" data-index="2">1</li>`)
+
+	t.Run("Count", func(t *testing.T) {
+		s, err := ennet.Expand(`p{${$ * 100 / n}% ${n - $} ${-(n+$)}}*4`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p>25% 3 -5</p><p>50% 2 -6</p><p>75% 1 -7</p><p>100% 0 -8</p>`)
+	})
+
+	t.Run("Pad", func(t *testing.T) {
+		s, err := ennet.Expand(`a${$$$@-*2}*2`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a004 /><a002 />`)
+	})
+
+	t.Run("NotExpression", func(t *testing.T) {
+		s, err := ennet.Expand(`a${$}*2`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a1>1</a1><a2>2</a2>`)
+
+		s, err = ennet.Expand(`p{${$*2}}`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p>${$*2}</p>`)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := ennet.Expand(`p{${$/($-1)}}*2`)
+		gotwant.TestError(t, err, "division by zero")
+
+		_, err = ennet.Expand(`p{${$*}}*2`)
+		gotwant.TestError(t, err, "invalid expression ${$*}")
+
+		_, err = ennet.Expand(`p{${($+1}}*2`)
+		gotwant.TestError(t, err, "invalid expression")
+	})
+}
+
+func TestNumbering(t *testing.T) {
+	t.Run("Base", func(t *testing.T) {
+		s, err := ennet.Expand(`li.i$@0*3`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<li class="i0" /><li class="i1" /><li class="i2" />`)
+
+		s, err = ennet.Expand(`li.i$@-0*3`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<li class="i2" /><li class="i1" /><li class="i0" />`)
+
+		s, err = ennet.Expand(`li.i$@2147483647~2147483647*2`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<li class="i2147483647" /><li class="i4294967294" />`)
+	})
+
+	t.Run("Step", func(t *testing.T) {
+		s, err := ennet.Expand(`li.i$@10~5*3`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<li class="i10" /><li class="i15" /><li class="i20" />`)
+
+		s, err = ennet.Expand(`li.i$$$@-10~5*3`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<li class="i020" /><li class="i015" /><li class="i010" />`)
+
+		s, err = ennet.Expand(`li{$~2|alpha}*3`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<li>a</li><li>c</li><li>e</li>`)
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		_, err := ennet.Expand(`p>li#$|nothing*2`)
+		gotwant.TestError(t, err, "unknown counter format nothing")
+		perr := err.(*ennet.ParseError)
+		gotwant.Test(t, [2]int{perr.Pos, perr.End}, [2]int{3, 17})
+
+		// not a numbering out of multiplications
+		s, err := ennet.Expand(`p{a $|b}`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p>a $|b</p>`)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, c := range []struct {
+			abbr     string
+			pos, end int
+			err      string
+		}{
+			{`li.i$@x*3`, 5, 7, "a number following $@ is required"},
+			{`li{a$~}*3`, 5, 7, "a number following ~ is required"},
+			{`li[x=$~0]`, 6, 9, "step must not be 0"},
+			{`li{${$*}}`, 4, 9, "invalid expression ${$*}"},
+			{`li{$@999999999999999999999}*3`, 4, 27, "the number following $@ is too large"},
+			{`li{$@-2147483648}*3`, 4, 17, "the number following $@ is too large"},
+			{`li.i$~99999999999*3`, 5, 18, "the number following ~ is too large"},
+		} {
+			_, err := ennet.Expand(c.abbr)
+			gotwant.TestError(t, err, c.err, gotwant.Desc(c.abbr))
+			perr := err.(*ennet.ParseError)
+			gotwant.Test(t, [2]int{perr.Pos, perr.End}, [2]int{c.pos, c.end}, gotwant.Desc(c.abbr))
+		}
+	})
+}
+
+func TestMarkup(t *testing.T) {
+	t.Run("Comment", func(t *testing.T) {
+		s, err := ennet.Expand(`ul>(!--{item $}+li)*2`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<ul><!--item 1--><li /><!--item 2--><li /></ul>`)
+
+		s, err = ennet.Expand(`!--{a--b-}`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<!--a- -b- -->`)
+	})
+
+	t.Run("CData", func(t *testing.T) {
+		s, err := ennet.Expand(`script>!cdata{if (a[b[0]]>1) x();}`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<script><![CDATA[if (a[b[0]]]]><![CDATA[>1) x();]]></script>`)
+
+		s, err = ennet.Expand(`p>!cdata{<&>}`, ennet.WithEscape(true))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p><![CDATA[<&>]]></p>`)
+	})
+
+	t.Run("ProcInst", func(t *testing.T) {
+		s, err := ennet.Expand(`?xml-stylesheet{href="a.css" type="text/css"}+root`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<?xml-stylesheet href="a.css" type="text/css"?><root />`)
+
+		s, err = ennet.Expand(`?php{echo 1 ?> 2;}`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<?php echo 1 ? > 2;?>`)
+	})
+
+	t.Run("Indent", func(t *testing.T) {
+		s, err := ennet.Expand(`div>!--{c}+p`, ennet.WithIndent("  "))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, "<div>\n  <!--c-->\n  <p />\n</div>")
+	})
+}
+
+func TestAttrOrder(t *testing.T) {
+	const abbr = `a[title=t href=/ data-x]#i.c*2`
+
+	t.Run("Source", func(t *testing.T) {
+		s, err := ennet.Expand(abbr)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a id="i" class="c" title="t" href="/" data-x="" /><a id="i" class="c" title="t" href="/" data-x="" />`)
+	})
+
+	t.Run("Alphabetical", func(t *testing.T) {
+		s, err := ennet.Expand(abbr, ennet.WithAttrOrder(ennet.AlphabeticalOrder))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a class="c" data-x="" href="/" id="i" title="t" /><a class="c" data-x="" href="/" id="i" title="t" />`)
+	})
+
+	t.Run("Priority", func(t *testing.T) {
+		s, err := ennet.Expand(abbr, ennet.WithAttrOrder(ennet.AttrPriority("href", "id")))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a href="/" id="i" title="t" data-x="" class="c" /><a href="/" id="i" title="t" data-x="" class="c" />`)
+	})
+}
+
+func TestMerge(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		s, err := ennet.Expand(`a#x#y.b.c.b[href=a href=b class="c d"]`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a id="y" class="b c d" href="b" />`)
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		s, err := ennet.Expand(`p[style="color: red" style="margin: 0"]`, ennet.WithMerge("style", ennet.MergeJoin("; ")))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p style="color: red; margin: 0" />`)
+
+		s, err = ennet.Expand(`a.b.b[rel=x rel=y]`, ennet.WithMerge("", ennet.MergeTokens), ennet.WithMerge("class", ennet.MergeJoin(" ")))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a class="b b" rel="x y" />`)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		s, err := ennet.Expand(`a#x#x`, ennet.WithMerge("id", ennet.MergeError))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a id="x" />`)
+
+		_, err = ennet.Expand(`a#x#y`, ennet.WithMerge("id", ennet.MergeError))
+		gotwant.TestError(t, err, "conflicting values of attribute id")
+		perr := err.(*ennet.ParseError)
+		gotwant.Test(t, [2]int{perr.Pos, perr.End}, [2]int{5, 6})
+	})
+}
+
+func TestBooleanAttributes(t *testing.T) {
+	t.Run("HTML", func(t *testing.T) {
+		s, err := ennet.Expand(`input[disabled checked value]`, ennet.WithMode(ennet.HTML))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<input type="" disabled checked value="">`)
+
+		s, err = ennet.Expand(`option[selected=selected]`, ennet.WithMode(ennet.HTML))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<option value="" selected="selected"></option>`)
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		s, err := ennet.Expand(`input[disabled checked value]`, ennet.WithMode(ennet.XHTML))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<input type="" disabled="disabled" checked="checked" value="" />`)
+
+		s, err = ennet.Expand(`input[disabled]`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<input disabled="disabled" />`)
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		s, err := ennet.Expand(`div[x-cloak hx-boost]`, ennet.WithMode(ennet.HTML), ennet.WithBooleanAttributes("x-cloak"))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<div x-cloak hx-boost=""></div>`)
+	})
+}
+
+func TestDefaultAttributes(t *testing.T) {
+	html := ennet.WithMode(ennet.HTML)
+
+	t.Run("HTML", func(t *testing.T) {
+		s, err := ennet.Expand(`a+img+input`, html)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a href=""></a><img src="" alt=""><input type="">`)
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		s, err := ennet.Expand(`a[href=/x title=t]#top+img[alt=A src="a.png"]+input[type=text type=email]`, html)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a id="top" href="/x" title="t"></a><img src="a.png" alt="A"><input type="email">`)
+
+		s, err = ennet.Expand(`input[type=a type=b]`, html, ennet.WithMerge("type", ennet.MergeError))
+		gotwant.TestError(t, err, "conflicting values of attribute type")
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		s, err := ennet.Expand(`a+img`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a /><img />`)
+
+		s, err = ennet.Expand(`a+img`, ennet.WithDefaultAttributes(ennet.HTMLDefaultAttributes()))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a href="" /><img src="" alt="" />`)
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		s, err := ennet.Expand(`a+button`, html, ennet.WithDefaultAttributes(map[string][]ennet.Attribute{
+			"button": {{Name: "type", Value: "button"}},
+		}))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a></a><button type="button"></button>`)
+
+		defaults := map[string][]ennet.Attribute{"a": {{Name: "rel", Value: "x"}}}
+		opt := ennet.WithDefaultAttributes(defaults)
+		defaults["a"][0].Value = "changed"
+		delete(defaults, "a")
+		s, err = ennet.Expand(`a`, html, opt)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a rel="x"></a>`)
+
+		hd := ennet.HTMLDefaultAttributes()
+		delete(hd, "a")
+		s, err = ennet.Expand(`a`, html)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a href=""></a>`)
+
+		s, err = ennet.Expand(`a`, html, ennet.WithDefaultAttributes(nil))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a></a>`)
+	})
+}
@@ -0,0 +1,145 @@
+// Package ennethttp serves abbreviation expansion as a JSON service.
+//
+// A request is a POST of a JSON object:
+//
+//	{"abbreviation": "ul>li*3", "mode": "html", "indent": "  ", "escape": true}
+//
+// and the response is
+//
+//	{"result": "<ul>..."}
+//
+// or, on errors,
+//
+//	{"error": {"message": "id name is required", "pos": 6, "end": 7}}
+//
+// pos and end are 1-based byte positions in the abbreviation, and present only for parse errors.
+//
+// data, an optional object of a request, gives the values of variables (${name}) in the abbreviation.
+package ennethttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/shu-go/ennet"
+)
+
+// Default limits used when the fields of Handler are zero.
+const (
+	DefaultMaxBodySize         = 64 * 1024
+	DefaultMaxAbbreviationSize = 4 * 1024
+	DefaultMaxMul              = 1000
+	DefaultMaxOutputSize       = 1024 * 1024
+)
+
+// Handler is an http.Handler that expands POSTed abbreviations.
+// The zero value is ready to use with the default limits.
+type Handler struct {
+	MaxBodySize         int64 // bytes of a request body
+	MaxAbbreviationSize int   // bytes of an abbreviation
+	MaxMul              int   // copies of a node by nested multiplications
+	MaxOutputSize       int   // bytes of an expansion
+}
+
+// Request is the body of a request.
+type Request struct {
+	Abbreviation string `json:"abbreviation"`
+	Mode         string `json:"mode,omitempty"`
+	Indent       string `json:"indent,omitempty"`
+	Escape       bool   `json:"escape,omitempty"`
+
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// Response is the body of a response.
+type Response struct {
+	Result string `json:"result,omitempty"`
+	Error  *Error `json:"error,omitempty"`
+}
+
+// Error is an error in a Response.
+type Error struct {
+	Message string `json:"message"`
+	Pos     int    `json:"pos,omitempty"`
+	End     int    `json:"end,omitempty"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, &Error{Message: "method not allowed"})
+		return
+	}
+
+	var req Request
+	body := http.MaxBytesReader(w, r.Body, or(h.MaxBodySize, DefaultMaxBodySize))
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, &Error{Message: "request body too large"})
+		} else {
+			writeError(w, http.StatusBadRequest, &Error{Message: "invalid request: " + err.Error()})
+		}
+		return
+	}
+
+	if max := or(h.MaxAbbreviationSize, DefaultMaxAbbreviationSize); len(req.Abbreviation) > max {
+		writeError(w, http.StatusRequestEntityTooLarge, &Error{Message: "abbreviation longer than " + strconv.Itoa(max) + " bytes"})
+		return
+	}
+
+	opts := []ennet.Option{
+		ennet.WithEscape(req.Escape),
+		ennet.WithIndent(req.Indent),
+		ennet.WithMaxMul(or(h.MaxMul, DefaultMaxMul)),
+		ennet.WithMaxOutput(or(h.MaxOutputSize, DefaultMaxOutputSize)),
+	}
+	if req.Mode != "" {
+		mode, err := ennet.ParseMode(req.Mode)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, &Error{Message: err.Error()})
+			return
+		}
+		opts = append(opts, ennet.WithMode(mode))
+	}
+	if req.Data != nil {
+		opts = append(opts, ennet.WithData(req.Data))
+	}
+
+	result, err := ennet.Expand(req.Abbreviation, opts...)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ennet.ErrOutputLimit) || errors.Is(err, ennet.ErrMulLimit) || errors.Is(err, ennet.ErrLoremLimit) {
+			status = http.StatusRequestEntityTooLarge
+		}
+
+		e := &Error{Message: err.Error()}
+		var perr *ennet.ParseError
+		if errors.As(err, &perr) {
+			e = &Error{Message: perr.Err.Error(), Pos: perr.Pos, End: perr.End}
+		}
+		writeError(w, status, e)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Response{Result: result})
+}
+
+func or[T int | int64](v, def T) T {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+func writeError(w http.ResponseWriter, status int, e *Error) {
+	writeJSON(w, status, Response{Error: e})
+}
+
+func writeJSON(w http.ResponseWriter, status int, resp Response) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
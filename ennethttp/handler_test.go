@@ -0,0 +1,113 @@
+package ennethttp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shu-go/ennet/ennethttp"
+	"github.com/shu-go/gotwant"
+)
+
+func post(h http.Handler, body string) (int, ennethttp.Response) {
+	req := httptest.NewRequest(http.MethodPost, "/expand", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp ennethttp.Response
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec.Code, resp
+}
+
+func TestHandler(t *testing.T) {
+	h := &ennethttp.Handler{}
+
+	t.Run("Expand", func(t *testing.T) {
+		status, resp := post(h, `{"abbreviation": "ul>li.item$*2"}`)
+		gotwant.Test(t, status, http.StatusOK)
+		gotwant.Test(t, resp.Result, `<ul><li class="item1" /><li class="item2" /></ul>`)
+		gotwant.Test(t, resp.Error, (*ennethttp.Error)(nil))
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		status, resp := post(h, `{"abbreviation": "p>br+a{&}", "mode": "html", "indent": "\t", "escape": true}`)
+		gotwant.Test(t, status, http.StatusOK)
+		gotwant.Test(t, resp.Result, "<p>\n\t<br>\n\t<a href=\"\">&amp;</a>\n</p>")
+
+		status, resp = post(h, `{"abbreviation": "a", "mode": "svg"}`)
+		gotwant.Test(t, status, http.StatusBadRequest)
+		gotwant.Test(t, resp.Error.Message, `unknown mode "svg"`)
+	})
+
+	t.Run("Data", func(t *testing.T) {
+		status, resp := post(h, `{"abbreviation": "a[href=${url}]{${label}}", "data": {"url": "/x", "label": "<X>"}}`)
+		gotwant.Test(t, status, http.StatusOK)
+		gotwant.Test(t, resp.Result, `<a href="/x">&lt;X&gt;</a>`)
+
+		status, resp = post(h, `{"abbreviation": "p{${label}}"}`)
+		gotwant.Test(t, status, http.StatusBadRequest)
+		gotwant.Test(t, *resp.Error, ennethttp.Error{Message: "undefined variable label", Pos: 2, End: 12})
+	})
+
+	t.Run("ParseError", func(t *testing.T) {
+		status, resp := post(h, `{"abbreviation": "ul>li#+a"}`)
+		gotwant.Test(t, status, http.StatusBadRequest)
+		gotwant.Test(t, *resp.Error, ennethttp.Error{Message: "id name is required", Pos: 7, End: 8})
+	})
+
+	t.Run("BadRequest", func(t *testing.T) {
+		status, resp := post(h, `{"abbreviation": `)
+		gotwant.Test(t, status, http.StatusBadRequest)
+		gotwant.Test(t, resp.Error.Message, "invalid request: unexpected EOF")
+
+		req := httptest.NewRequest(http.MethodGet, "/expand", nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		gotwant.Test(t, rec.Code, http.StatusMethodNotAllowed)
+		gotwant.Test(t, rec.Header().Get("Allow"), http.MethodPost)
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		h := &ennethttp.Handler{
+			MaxBodySize:         100,
+			MaxAbbreviationSize: 20,
+			MaxMul:              10,
+			MaxOutputSize:       50,
+		}
+
+		status, resp := post(h, `{"abbreviation": "`+strings.Repeat("a+", 50)+`a"}`)
+		gotwant.Test(t, status, http.StatusRequestEntityTooLarge)
+		gotwant.Test(t, resp.Error.Message, "request body too large")
+
+		status, resp = post(h, `{"abbreviation": "`+strings.Repeat("a+", 10)+`a"}`)
+		gotwant.Test(t, status, http.StatusRequestEntityTooLarge)
+		gotwant.Test(t, resp.Error.Message, "abbreviation longer than 20 bytes")
+
+		status, resp = post(h, `{"abbreviation": "a*11"}`)
+		gotwant.Test(t, status, http.StatusRequestEntityTooLarge)
+		gotwant.Test(t, *resp.Error, ennethttp.Error{Message: "multiplication exceeds the limit", Pos: 1, End: 5})
+
+		status, resp = post(h, `{"abbreviation": "a*10+b*10"}`)
+		gotwant.Test(t, status, http.StatusRequestEntityTooLarge)
+		gotwant.Test(t, resp.Error.Message, "output exceeds the limit")
+
+		status, resp = post(h, `{"abbreviation": "(a*4)*3"}`)
+		gotwant.Test(t, status, http.StatusRequestEntityTooLarge)
+		gotwant.Test(t, resp.Error.Message, "multiplication exceeds the limit")
+	})
+
+	t.Run("NestedMul", func(t *testing.T) {
+		start := time.Now()
+		status, resp := post(&ennethttp.Handler{}, `{"abbreviation": "((({}*1000)*1000)*1000)*1000"}`)
+		gotwant.Test(t, status, http.StatusRequestEntityTooLarge)
+		gotwant.Test(t, resp.Error.Message, "multiplication exceeds the limit")
+		gotwant.Test(t, time.Since(start) < time.Second, true)
+
+		status, resp = post(&ennethttp.Handler{}, `{"abbreviation": "lorem999999999"}`)
+		gotwant.Test(t, status, http.StatusRequestEntityTooLarge)
+		gotwant.Test(t, resp.Error.Message, "words of dummy text exceed the limit")
+	})
+}
@@ -0,0 +1,146 @@
+// Package ennettmpl provides template functions that expand abbreviations.
+//
+//	t := template.New("page").Funcs(ennettmpl.HTMLFuncMap())
+//	template.Must(t.Parse(`{{ennet "ul>li.item$*3"}}`))
+//
+// An optional second argument gives the data of variables.
+//
+//	{{ennet "a[href=/users/${ID}]{${Name}}" .User}}
+package ennettmpl
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/shu-go/ennet"
+)
+
+// FuncMap returns the function "ennet" for text/template.
+// It expands an abbreviation with opts and returns a string.
+func FuncMap(opts ...ennet.Option) texttemplate.FuncMap {
+	return texttemplate.FuncMap{
+		"ennet": func(abbr string, data ...any) (string, error) {
+			return ennet.Expand(abbr, withData(opts, data)...)
+		},
+	}
+}
+
+// HTMLFuncMap returns the function "ennet" for html/template.
+//
+// It expands an abbreviation in HTML mode with escaping, then opts.
+// The result is template.HTML only if all of its texts and attribute values
+// are escaped correctly, that is, if it has no unsafe names, no scripts or styles,
+// no event handler attributes, no script URLs,
+// and no comments, CDATA sections or processing instructions.
+// Variables are not allowed in names, nor at the scheme of URLs.
+// Otherwise the result is a string, and html/template escapes it as a whole.
+func HTMLFuncMap(opts ...ennet.Option) htmltemplate.FuncMap {
+	opts = append([]ennet.Option{ennet.WithMode(ennet.HTML)}, opts...)
+	opts = append(opts, ennet.WithEscape(true))
+
+	return htmltemplate.FuncMap{
+		"ennet": func(abbr string, data ...any) (any, error) {
+			expanded, err := ennet.Expand(abbr, withData(opts, data)...)
+			if err != nil {
+				return nil, err
+			}
+
+			nb := ennet.NewNodeBuilder(nil)
+			if err := ennet.ParseWith([]byte(abbr), &nb, opts...); err != nil {
+				return nil, err
+			}
+			if !isSafe(nb.Root) {
+				return expanded, nil
+			}
+			return htmltemplate.HTML(expanded), nil
+		},
+	}
+}
+
+func withData(opts []ennet.Option, data []any) []ennet.Option {
+	if len(data) == 0 {
+		return opts
+	}
+	return append(opts[:len(opts):len(opts)], ennet.WithData(data[0]))
+}
+
+// isSafe reports whether escaping texts and attribute values is enough for n.
+func isSafe(n *ennet.Node) bool {
+	switch n.Type {
+	case ennet.Comment, ennet.CData, ennet.ProcInst:
+		return false
+	}
+	if n.Type == ennet.Element {
+		if !isSafeName(n.Data) {
+			return false
+		}
+		switch strings.ToLower(n.Data) {
+		case "script", "style", "iframe", "object", "embed", "base", "meta":
+			return false
+		}
+
+		for _, attr := range n.Attributes {
+			name := strings.ToLower(attr.Name)
+			if !isSafeName(name) || strings.HasPrefix(name, "on") || name == "style" || name == "srcdoc" {
+				return false
+			}
+			if isURLAttribute(name) && !isSafeURL(attr.Value) {
+				return false
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if !isSafe(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func isSafeName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-' || c == '_' || c == ':' || c == '$' || c == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isURLAttribute(name string) bool {
+	switch name {
+	case "href", "src", "action", "formaction", "xlink:href", "poster", "cite", "data", "background", "srcset":
+		return true
+	default:
+		return false
+	}
+}
+
+func isSafeURL(url string) bool {
+	url = strings.ToLower(strings.TrimSpace(url))
+	if i := strings.Index(url, "${"); i != -1 {
+		// a variable may give a scheme, unless the URL before it is relative or has one
+		if !strings.ContainsAny(url[:i], ":/?#") {
+			return false
+		}
+		url = url[:i]
+	}
+	scheme, _, found := strings.Cut(url, ":")
+	if !found || strings.ContainsAny(scheme, "/?#") {
+		return true // relative
+	}
+	switch scheme {
+	case "http", "https", "mailto", "tel":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,119 @@
+package ennettmpl_test
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	"testing"
+	texttemplate "text/template"
+
+	"github.com/shu-go/ennet"
+	"github.com/shu-go/ennet/ennettmpl"
+	"github.com/shu-go/gotwant"
+)
+
+func executeHTML(t *testing.T, text string, data any) (string, error) {
+	t.Helper()
+
+	tmpl, err := htmltemplate.New("").Funcs(ennettmpl.HTMLFuncMap()).Parse(text)
+	gotwant.TestError(t, err, nil)
+	var b strings.Builder
+	err = tmpl.Execute(&b, data)
+	return b.String(), err
+}
+
+func TestFuncMap(t *testing.T) {
+	tmpl, err := texttemplate.New("").Funcs(ennettmpl.FuncMap(ennet.WithMode(ennet.XHTML))).Parse(`{{ennet "ul>li.item$*2>br"}}`)
+	gotwant.TestError(t, err, nil)
+	var b strings.Builder
+	gotwant.TestError(t, tmpl.Execute(&b, nil), nil)
+	gotwant.Test(t, b.String(), `<ul><li class="item1"><br /></li><li class="item2"><br /></li></ul>`)
+
+	tmpl, err = texttemplate.New("").Funcs(ennettmpl.FuncMap()).Parse(`{{ennet "a[href=${URL}]{${Label}}" .}}`)
+	gotwant.TestError(t, err, nil)
+	b.Reset()
+	gotwant.TestError(t, tmpl.Execute(&b, struct{ URL, Label string }{"/x", "<X>"}), nil)
+	gotwant.Test(t, b.String(), `<a href="/x">&lt;X&gt;</a>`)
+}
+
+func TestHTMLFuncMap(t *testing.T) {
+	t.Run("Safe", func(t *testing.T) {
+		s, err := executeHTML(t, `<div>{{ennet "ul>li.item$*2>a[href=/x$]{<&>}"}}</div>`, nil)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<div><ul><li class="item1"><a href="/x1">&lt;&amp;&gt;</a></li><li class="item2"><a href="/x2">&lt;&amp;&gt;</a></li></ul></div>`)
+	})
+
+	t.Run("FromData", func(t *testing.T) {
+		s, err := executeHTML(t, `{{ennet .}}`, `p>{"quoted"}+img[alt='"x"']`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p>"quoted"<img src="" alt="&quot;x&quot;"></p>`)
+	})
+
+	t.Run("Variables", func(t *testing.T) {
+		data := map[string]any{"ID": 1, "Name": `<script>"x"</script>`, "URL": "javascript:x"}
+
+		s, err := executeHTML(t, `{{ennet "a[href=/users/${ID} title=${Name}]{${Name}}" .}}`, data)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a href="/users/1" title="&lt;script&gt;&quot;x&quot;&lt;/script&gt;">&lt;script&gt;"x"&lt;/script&gt;</a>`)
+
+		s, err = executeHTML(t, `{{ennet "a[href=${URL}]" .}}`, data)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `&lt;a href=&#34;javascript:x&#34;&gt;&lt;/a&gt;`)
+	})
+
+	t.Run("Unsafe", func(t *testing.T) {
+		s, err := executeHTML(t, `{{ennet .}}`, `script{alert(1)}`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `&lt;script&gt;alert(1)&lt;/script&gt;`)
+
+		s, err = executeHTML(t, `{{ennet .}}`, `a[onclick=x]`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `&lt;a href=&#34;&#34; onclick=&#34;x&#34;&gt;&lt;/a&gt;`)
+
+		s, err = executeHTML(t, `{{ennet .}}`, `a[href=javascript:x]`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `&lt;a href=&#34;javascript:x&#34;&gt;&lt;/a&gt;`)
+
+		s, err = executeHTML(t, `{{ennet .}}`, `p>!--{[if IE]><script>x</script><![endif]}`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, strings.HasPrefix(s, `&lt;p&gt;&lt;!--`), true)
+
+		s, err = executeHTML(t, `{{ennet .}}`, `a[x"=1]`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, strings.HasPrefix(s, `&lt;a`), true)
+	})
+
+	t.Run("Fragments", func(t *testing.T) {
+		fragments := ennet.WithFragments(ennet.FragmentMap(map[string]string{"x": "script{alert(1)}", "y": "em{y}"}))
+
+		tmpl, err := htmltemplate.New("").Funcs(ennettmpl.HTMLFuncMap(fragments)).Parse(`{{ennet .}}`)
+		gotwant.TestError(t, err, nil)
+		var b strings.Builder
+		gotwant.TestError(t, tmpl.Execute(&b, `p>@x`), nil)
+		gotwant.Test(t, b.String(), `&lt;p&gt;&lt;script&gt;alert(1)&lt;/script&gt;&lt;/p&gt;`)
+
+		b.Reset()
+		gotwant.TestError(t, tmpl.Execute(&b, `p>@y`), nil)
+		gotwant.Test(t, b.String(), `<p><em>y</em></p>`)
+	})
+
+	t.Run("Macros", func(t *testing.T) {
+		var m ennet.Macros
+		gotwant.TestError(t, m.Define(`card(title) = div.card>h2{$title}`), nil)
+		gotwant.TestError(t, m.Define(`evil() = script{alert(1)}`), nil)
+
+		tmpl, err := htmltemplate.New("").Funcs(ennettmpl.HTMLFuncMap(ennet.WithMacros(&m))).Parse(`{{ennet .}}`)
+		gotwant.TestError(t, err, nil)
+		var b strings.Builder
+		gotwant.TestError(t, tmpl.Execute(&b, `card("Hi")`), nil)
+		gotwant.Test(t, b.String(), `<div class="card"><h2>Hi</h2></div>`)
+
+		b.Reset()
+		gotwant.TestError(t, tmpl.Execute(&b, `p>evil()`), nil)
+		gotwant.Test(t, b.String(), `&lt;p&gt;&lt;script&gt;alert(1)&lt;/script&gt;&lt;/p&gt;`)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := executeHTML(t, `{{ennet "a#"}}`, nil)
+		gotwant.TestError(t, err, "id name is required")
+	})
+}
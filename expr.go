@@ -0,0 +1,213 @@
+package ennet
+
+import (
+	"errors"
+	"strings"
+)
+
+// placeholderLen returns the length of the variable or the expression at the start of s,
+// or 0 if s does not start with them.
+func placeholderLen(s string) int {
+	if n := variableLen(s); n > 0 {
+		return n
+	}
+	return expressionLen(s)
+}
+
+// expressionLen returns the length of the expression (${$*10}) at the start of s,
+// or 0 if s does not start with an expression.
+//
+// An expression has numbering placeholders and operators, and optionally a format (|roman),
+// so that ${ITEM$} and ${$} are not expressions.
+func expressionLen(s string) int {
+	if !strings.HasPrefix(s, "${") {
+		return 0
+	}
+
+	hasPH, hasOp := false, false
+	for i := 2; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '}':
+			if hasPH && hasOp {
+				return i + 1
+			}
+			return 0
+		case c == '$':
+			hasPH = true
+		case c == '-':
+			if s[i-1] != '@' {
+				hasOp = true
+			}
+		case c == '+' || c == '*' || c == '/' || c == '%' || c == '(' || c == ')' || c == 'n':
+			hasOp = true
+		case c == '|':
+			// ${$*2|roman}
+			n := counterFormatLen(s[i+1:])
+			if n == 0 || i+1+n >= len(s) || s[i+1+n] != '}' || !hasPH || !hasOp {
+				return 0
+			}
+			return i + 2 + n
+		case c == '@' || c == ' ' || '0' <= c && c <= '9':
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+var errDivisionByZero = errors.New("division by zero")
+
+// evalExpression evaluates expr (the inside of ${...}) in iter.
+//
+//	expr    = term, { ("+" | "-"), term };
+//	term    = unary, { ("*" | "/" | "%"), unary };
+//	unary   = [ "-" ], primary;
+//	primary = NUMBER | numbering | "n" | "(", expr, ")";
+//
+// numbering is $, $$@-3 and so on, and n is the count of the iteration.
+// pad is the longest numbering, for zero padding as $$.
+func evalExpression(expr string, iter iteration) (val, pad int, err error) {
+	e := exprEvaluator{s: expr, iter: iter}
+	defer func() {
+		if r := recover(); r != nil {
+			rerr, ok := r.(error)
+			if !ok {
+				panic(r)
+			}
+			err = rerr
+		}
+	}()
+
+	val = e.expr()
+	if e.skipSpace(); e.i < len(e.s) {
+		e.fail()
+	}
+	return val, e.pad, nil
+}
+
+// checkExpression checks the syntax of expr.
+func checkExpression(expr string) error {
+	expr, format, found := strings.Cut(expr, "|")
+	if found {
+		if _, found := lookupCounterFormat(format); !found {
+			return errors.New("unknown counter format " + format)
+		}
+	}
+
+	_, _, err := evalExpression(expr, iteration{index: 0, count: 1})
+	if err == errDivisionByZero {
+		return nil // depends on the iteration
+	}
+	return err
+}
+
+type exprEvaluator struct {
+	s    string
+	i    int
+	iter iteration
+	pad  int
+}
+
+func (e *exprEvaluator) fail() {
+	panic(errors.New("invalid expression ${" + e.s + "}"))
+}
+
+func (e *exprEvaluator) skipSpace() {
+	for e.i < len(e.s) && e.s[e.i] == ' ' {
+		e.i++
+	}
+}
+
+// next returns the next byte after spaces, or 0.
+func (e *exprEvaluator) next() byte {
+	e.skipSpace()
+	if e.i < len(e.s) {
+		return e.s[e.i]
+	}
+	return 0
+}
+
+func (e *exprEvaluator) expr() int {
+	val := e.term()
+	for {
+		switch e.next() {
+		case '+':
+			e.i++
+			val += e.term()
+		case '-':
+			e.i++
+			val -= e.term()
+		default:
+			return val
+		}
+	}
+}
+
+func (e *exprEvaluator) term() int {
+	val := e.unary()
+	for {
+		op := e.next()
+		if op != '*' && op != '/' && op != '%' {
+			return val
+		}
+		e.i++
+		rhs := e.unary()
+		switch {
+		case op == '*':
+			val *= rhs
+		case rhs == 0:
+			panic(errDivisionByZero)
+		case op == '/':
+			val /= rhs
+		default:
+			val %= rhs
+		}
+	}
+}
+
+func (e *exprEvaluator) unary() int {
+	if e.next() == '-' {
+		e.i++
+		return -e.primary()
+	}
+	return e.primary()
+}
+
+func (e *exprEvaluator) primary() int {
+	switch c := e.next(); {
+	case c == '(':
+		e.i++
+		val := e.expr()
+		if e.next() != ')' {
+			e.fail()
+		}
+		e.i++
+		return val
+
+	case c == 'n':
+		e.i++
+		return e.iter.count
+
+	case c == '$':
+		seg, err := parseNumbering(e.s[e.i:])
+		if err != nil {
+			panic(err)
+		}
+		e.i += len(seg.literal)
+		e.pad = max(e.pad, seg.pad)
+		return seg.number(e.iter)
+
+	case '0' <= c && c <= '9':
+		val := 0
+		for e.i < len(e.s) && '0' <= e.s[e.i] && e.s[e.i] <= '9' {
+			val = val*10 + int(e.s[e.i]-'0')
+			e.i++
+		}
+		return val
+
+	default:
+		e.fail()
+		return 0
+	}
+}
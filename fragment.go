@@ -0,0 +1,125 @@
+package ennet
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FragmentFunc returns the abbreviation of the fragment name.
+//
+// A fragment is referred as @name in an abbreviation (@header+main>@sidebar),
+// and parsed into the position of the reference, as a group.
+type FragmentFunc func(name string) (string, error)
+
+// ErrNoFragment is returned by the FragmentFunc of FragmentMap for an unknown name.
+var ErrNoFragment = errors.New("fragment not found")
+
+// FragmentMap returns a FragmentFunc that looks up m.
+func FragmentMap(m map[string]string) FragmentFunc {
+	return func(name string) (string, error) {
+		abbr, found := m[name]
+		if !found {
+			return "", ErrNoFragment
+		}
+		return abbr, nil
+	}
+}
+
+// FragmentFS returns a FragmentFunc that reads the file name+ext in fsys,
+// such as an embed.FS.
+// Line breaks in a file are spaces, so an abbreviation can be written in lines.
+func FragmentFS(fsys fs.FS, ext string) FragmentFunc {
+	return func(name string) (string, error) {
+		b, err := fs.ReadFile(fsys, name+ext)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+}
+
+// WithFragments makes Expand resolve @name by f.
+// Without it, @name is an element.
+func WithFragments(f FragmentFunc) Option {
+	return func(o *options) {
+		o.fragments = f
+	}
+}
+
+// IncludeError is an error in the fragment Name.
+// Err is a *ParseError located in the fragment, or an error of the FragmentFunc.
+//
+// Expand reports it in a ParseError located at the reference.
+type IncludeError struct {
+	Name string
+	Err  error
+}
+
+func (e *IncludeError) Error() string {
+	var perr *ParseError
+	if errors.As(e.Err, &perr) {
+		return "@" + e.Name + ":" + strconv.Itoa(perr.Pos) + ": " + perr.Err.Error()
+	}
+	return "@" + e.Name + ": " + e.Err.Error()
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// include parses @name if fragments are given.
+func (p *Parser) include() bool {
+	if p.fragments == nil {
+		return false
+	}
+
+	ref := p.lexer.Next()
+	name, found := strings.CutPrefix(ref.Text, "@")
+	if ref.Type != STRING || !found || name == "" {
+		p.lexer.Back()
+		return false
+	}
+
+	if slices.Contains(p.includes, name) {
+		cycle := append(slices.Clone(p.includes), name)
+		p.fail(ref, &IncludeError{Name: name, Err: errors.New("include cycle @" + strings.Join(cycle, " > @"))})
+	}
+	abbr, err := p.fragments(name)
+	if err != nil {
+		p.fail(ref, &IncludeError{Name: name, Err: err})
+	}
+
+	p.at(ref, ref)
+	if err := p.builder.GroupBegin(); err != nil {
+		p.fail(ref, err)
+	}
+
+	sub := Parser{
+		lexer:     NewLexer([]byte(abbr)),
+		builder:   p.builder,
+		pb:        p.pb,
+		macros:    p.macros,
+		fragments: p.fragments,
+		includes:  append(slices.Clip(p.includes), name),
+		span:      p.span,
+	}
+	if sub.span == [2]int{} {
+		sub.span = [2]int{ref.Pos, tokenEnd(p.lexer.in, ref)}
+	}
+	if perr := parseNested(&sub); perr != nil {
+		p.fail(ref, &IncludeError{Name: name, Err: perr})
+	}
+
+	p.at(ref, ref)
+	if err := p.builder.GroupEnd(); err != nil {
+		p.fail(ref, err)
+	}
+
+	if p.lexer.Peek().Type == MULT {
+		p.multiplication()
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package ennet_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/shu-go/ennet"
+	"github.com/shu-go/gotwant"
+)
+
+func TestFragments(t *testing.T) {
+	fragments := ennet.FragmentMap(map[string]string{
+		"header":  "header>h1{Title}",
+		"sidebar": "aside>ul>li*2",
+		"content": "article>p",
+		"page":    "@header+main>@sidebar+@content",
+		"broken":  "div>p#",
+		"outer":   "section>@broken",
+		"a":       "p>@b",
+		"b":       "div>@a",
+	})
+
+	t.Run("Include", func(t *testing.T) {
+		s, err := ennet.Expand(`@page`, ennet.WithFragments(fragments))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<header><h1>Title</h1></header><main><aside><ul><li /><li /></ul></aside><article><p /></article></main>`)
+
+		s, err = ennet.Expand(`div>@content*2+footer`, ennet.WithFragments(fragments))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<div><article><p /></article><article><p /></article><footer /></div>`)
+
+		s, err = ennet.Expand(`@content`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<@content />`)
+	})
+
+	t.Run("FS", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"parts/nav.ennet": {Data: []byte("nav>\n  a[href=/]{Home}\n")},
+		}
+		s, err := ennet.Expand(`body>@parts/nav`, ennet.WithFragments(ennet.FragmentFS(fsys, ".ennet")))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<body><nav><a href="/">Home</a></nav></body>`)
+
+		_, err = ennet.Expand(`body>@nothing`, ennet.WithFragments(ennet.FragmentFS(fsys, ".ennet")))
+		gotwant.TestError(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := ennet.Expand(`p+@nothing`, ennet.WithFragments(fragments))
+		gotwant.TestError(t, err, ennet.ErrNoFragment)
+		gotwant.TestError(t, err, "@nothing: fragment not found")
+		perr := err.(*ennet.ParseError)
+		gotwant.Test(t, perr.Pos, 3)
+		gotwant.Test(t, perr.End, 11)
+
+		// located in the fragments
+		_, err = ennet.Expand(`p+@outer`, ennet.WithFragments(fragments))
+		gotwant.TestError(t, err, "@outer:9: @broken:7: id name is required at 3")
+		var ierr *ennet.IncludeError
+		gotwant.Test(t, errors.As(err, &ierr), true)
+		gotwant.Test(t, ierr.Name, "outer")
+		errors.As(ierr.Err, &ierr)
+		gotwant.Test(t, ierr.Name, "broken")
+		perr = ierr.Err.(*ennet.ParseError)
+		gotwant.Test(t, perr.Pos, 7)
+
+		_, err = ennet.Expand(`@a`, ennet.WithFragments(fragments))
+		gotwant.TestError(t, err, "include cycle @a > @b > @a")
+	})
+}
@@ -0,0 +1,201 @@
+package ennet
+
+import "strings"
+
+// SpanKind classifies a Span.
+type SpanKind uint8
+
+const (
+	SpanTag SpanKind = iota
+	SpanID
+	SpanClass
+	SpanAttrName
+	SpanAttrValue
+	SpanText
+	SpanOperator
+	SpanMultiplier
+	SpanPlaceholder
+	SpanPunctuation
+	SpanMarkup   // !--, !cdata and ?target
+	SpanFragment // @name
+	SpanMacro    // the name of a macro call
+)
+
+var spanKind2String = map[SpanKind]string{
+	SpanTag:         "tag",
+	SpanID:          "id",
+	SpanClass:       "class",
+	SpanAttrName:    "attribute name",
+	SpanAttrValue:   "attribute value",
+	SpanText:        "text",
+	SpanOperator:    "operator",
+	SpanMultiplier:  "multiplier",
+	SpanPlaceholder: "placeholder",
+	SpanPunctuation: "punctuation",
+	SpanMarkup:      "markup",
+	SpanFragment:    "fragment",
+	SpanMacro:       "macro",
+}
+
+func (k SpanKind) String() string {
+	if s, found := spanKind2String[k]; found {
+		return s
+	}
+	return "???"
+}
+
+// Span is a classified range of an abbreviation.
+// Pos and End are 1-based like Token.Pos, and End is exclusive.
+type Span struct {
+	Kind     SpanKind
+	Pos, End int
+}
+
+// Classify splits abbr into Spans for syntax highlighting.
+//
+// Unlike Token, a Span tells whether a string is a tag, an attribute name or a value.
+// "#" and "." are included in SpanID and SpanClass, "*" in SpanMultiplier,
+// and placeholders ($$@-3, ${$*10}) and variables (${name}) are split out as SpanPlaceholder.
+// A string just before "(" is a macro call (SpanMacro), and its arguments are SpanText.
+// Spans are in order and do not cover spaces.
+// Classify does not validate abbr; an unterminated text is a text up to the end.
+func Classify(abbr string) []Span {
+	in := []byte(abbr)
+	l := NewLexer(in)
+	defer l.Close()
+
+	c := classifier{in: in}
+	expect := SpanTag // kind of the next STRING
+	inAttr := false
+	call, inArgs := false, false // a macro call, and in its arguments
+	prefix := 0                  // position of the pending "#", "." or "*"
+
+	for {
+		start := l.offset + 1
+		for start <= len(in) && isSpace(in[start-1]) {
+			start++
+		}
+
+		tok := l.Next()
+		end := tokenEnd(in, tok)
+
+		if prefix != 0 && tok.Type != STRING {
+			c.add(expect, prefix, prefix+1)
+			prefix = 0
+		}
+		if inArgs && (tok.Type == STRING || tok.Type == TEXT || tok.Type == QTEXT) {
+			c.addLiteral(SpanText, tok.Pos, end) // arguments are literal
+			continue
+		}
+
+		switch tok.Type {
+		case EOF:
+			return c.spans
+
+		case ERR:
+			if inAttr {
+				c.add(SpanAttrValue, start, len(in)+1)
+			} else {
+				c.add(SpanText, start, len(in)+1)
+			}
+			return c.spans
+
+		case CHILD, SIBLING, CLIMBUP:
+			c.add(SpanOperator, tok.Pos, end)
+			expect = SpanTag
+		case GROUPBEGIN:
+			c.add(SpanPunctuation, tok.Pos, end)
+			expect = SpanTag
+			inArgs, call = call, false
+		case GROUPEND:
+			c.add(SpanPunctuation, tok.Pos, end)
+			inArgs = false
+		case ID:
+			prefix = tok.Pos
+			expect = SpanID
+		case CLASS:
+			prefix = tok.Pos
+			if expect != SpanMultiplier { // *@a.b
+				expect = SpanClass
+			}
+		case MULT:
+			prefix = tok.Pos
+			expect = SpanMultiplier
+		case ATTRBEGIN:
+			c.add(SpanPunctuation, tok.Pos, end)
+			inAttr = true
+			expect = SpanAttrName
+		case ATTREND:
+			c.add(SpanPunctuation, tok.Pos, end)
+			inAttr = false
+		case EQ:
+			c.add(SpanPunctuation, tok.Pos, end)
+			expect = SpanAttrValue
+
+		case STRING:
+			pos := tok.Pos
+			if prefix != 0 {
+				pos = prefix
+				prefix = 0
+			}
+			kind := expect
+			switch {
+			case expect != SpanTag:
+			case tok.Text == "!--" || tok.Text == "!cdata" || strings.HasPrefix(tok.Text, "?"):
+				kind = SpanMarkup
+			case strings.HasPrefix(tok.Text, "@"):
+				kind = SpanFragment
+			default:
+				if next := l.Peek(); next.Type == GROUPBEGIN && next.Pos == end {
+					kind = SpanMacro
+					call = true
+				}
+			}
+			c.add(kind, pos, end)
+			if inAttr {
+				expect = SpanAttrName
+			}
+		case TEXT:
+			c.add(SpanText, tok.Pos, end)
+		case QTEXT:
+			if inAttr {
+				c.add(SpanAttrValue, tok.Pos, end)
+				expect = SpanAttrName
+			} else {
+				c.add(SpanText, tok.Pos, end)
+			}
+		}
+	}
+}
+
+type classifier struct {
+	in    []byte
+	spans []Span
+}
+
+// add adds a span of kind, splitting out placeholders.
+func (c *classifier) add(kind SpanKind, pos, end int) {
+	if kind == SpanOperator || kind == SpanPunctuation || kind == SpanMultiplier {
+		c.addLiteral(kind, pos, end)
+		return
+	}
+
+	pSegs := segmentSlicePool.Get().(*[]segment)
+	segments := parseTemplateInto(unsafeString(c.in[pos-1:end-1]), (*pSegs)[:0])
+	for _, seg := range segments {
+		segEnd := pos + len(seg.literal)
+		if seg.isPH || seg.isVar || seg.isExpr {
+			c.spans = append(c.spans, Span{Kind: SpanPlaceholder, Pos: pos, End: segEnd})
+		} else {
+			c.spans = append(c.spans, Span{Kind: kind, Pos: pos, End: segEnd})
+		}
+		pos = segEnd
+	}
+	*pSegs = segments
+	segmentSlicePool.Put(pSegs)
+}
+
+// addLiteral adds a span of kind as is.
+func (c *classifier) addLiteral(kind SpanKind, pos, end int) {
+	c.spans = append(c.spans, Span{Kind: kind, Pos: pos, End: end})
+}
@@ -0,0 +1,159 @@
+package ennet_test
+
+import (
+	"testing"
+
+	"github.com/shu-go/ennet"
+	"github.com/shu-go/gotwant"
+)
+
+func classified(abbr string) []string {
+	var s []string
+	for _, span := range ennet.Classify(abbr) {
+		s = append(s, span.Kind.String()+" "+abbr[span.Pos-1:span.End-1])
+	}
+	return s
+}
+
+func TestClassify(t *testing.T) {
+	t.Run("Element", func(t *testing.T) {
+		gotwant.Test(t, classified(`a#id.cls[href="x" disabled title=t]{text}`), []string{
+			"tag a",
+			"id #id",
+			"class .cls",
+			"punctuation [",
+			"attribute name href",
+			"punctuation =",
+			`attribute value "x"`,
+			"attribute name disabled",
+			"attribute name title",
+			"punctuation =",
+			"attribute value t",
+			"punctuation ]",
+			"text {text}",
+		})
+	})
+
+	t.Run("Operators", func(t *testing.T) {
+		gotwant.Test(t, classified(`ul > (li+li)*2^p`), []string{
+			"tag ul",
+			"operator >",
+			"punctuation (",
+			"tag li",
+			"operator +",
+			"tag li",
+			"punctuation )",
+			"multiplier *2",
+			"operator ^",
+			"tag p",
+		})
+	})
+
+	t.Run("Placeholder", func(t *testing.T) {
+		gotwant.Test(t, classified(`h$.item$$@-3{No.$}*3`), []string{
+			"tag h",
+			"placeholder $",
+			"class .item",
+			"placeholder $$@-3",
+			"text {No.",
+			"placeholder $",
+			"text }",
+			"multiplier *3",
+		})
+	})
+
+	t.Run("Variable", func(t *testing.T) {
+		gotwant.Test(t, classified(`a[href=/${id}]{${name:none}}`), []string{
+			"tag a",
+			"punctuation [",
+			"attribute name href",
+			"punctuation =",
+			"attribute value /",
+			"placeholder ${id}",
+			"punctuation ]",
+			"text {",
+			"placeholder ${name:none}",
+			"text }",
+		})
+	})
+
+	t.Run("Expression", func(t *testing.T) {
+		gotwant.Test(t, classified(`li.w${$*10}*3`), []string{
+			"tag li",
+			"class .w",
+			"placeholder ${$*10}",
+			"multiplier *3",
+		})
+	})
+
+	t.Run("MulData", func(t *testing.T) {
+		gotwant.Test(t, classified(`li*@.a.b{${.c}}`), []string{
+			"tag li",
+			"multiplier *@",
+			"multiplier .a",
+			"multiplier .b",
+			"text {",
+			"placeholder ${.c}",
+			"text }",
+		})
+	})
+
+	t.Run("Markup", func(t *testing.T) {
+		gotwant.Test(t, classified(`?xml-stylesheet{a}+!--{b}+!cdata{c}`), []string{
+			"markup ?xml-stylesheet",
+			"text {a}",
+			"operator +",
+			"markup !--",
+			"text {b}",
+			"operator +",
+			"markup !cdata",
+			"text {c}",
+		})
+	})
+
+	t.Run("Fragment", func(t *testing.T) {
+		gotwant.Test(t, classified(`main>@sidebar*2`), []string{
+			"tag main",
+			"operator >",
+			"fragment @sidebar",
+			"multiplier *2",
+		})
+	})
+
+	t.Run("Macro", func(t *testing.T) {
+		gotwant.Test(t, classified(`ul>card(Hi $, "a")*2+(li)`), []string{
+			"tag ul",
+			"operator >",
+			"macro card",
+			"punctuation (",
+			"text Hi",
+			"text $,",
+			`text "a"`,
+			"punctuation )",
+			"multiplier *2",
+			"operator +",
+			"punctuation (",
+			"tag li",
+			"punctuation )",
+		})
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		gotwant.Test(t, classified(`a[title="x`), []string{
+			"tag a",
+			"punctuation [",
+			"attribute name title",
+			"punctuation =",
+			`attribute value "x`,
+		})
+		gotwant.Test(t, classified(`p>{Hello `), []string{
+			"tag p",
+			"operator >",
+			"text {Hello ",
+		})
+		gotwant.Test(t, classified(`a#`), []string{
+			"tag a",
+			"id #",
+		})
+	})
+}
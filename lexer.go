@@ -29,6 +29,9 @@ type Lexer struct {
 	// else: next is from (*tokens)[scanpos], then scanpos++
 	tokens  *[]Token
 	scanpos int
+
+	// params of the macro being expanded, substituted in STRING, TEXT and QTEXT
+	params map[string]string
 }
 
 func NewLexer(b []byte) *Lexer {
@@ -54,7 +57,7 @@ func (l *Lexer) Next() Token {
 	var tok Token
 	scanning := *l.tokens
 	if l.scanpos == len(scanning) {
-		tok = l.scanNext()
+		tok = l.scan()
 		*l.tokens = append(*l.tokens, tok)
 	} else { // l.scanpos < len(scanning)
 		tok = scanning[l.scanpos]
@@ -77,11 +80,19 @@ func (l *Lexer) Peek() Token {
 		return scanning[l.scanpos]
 	}
 
-	tok := l.scanNext()
+	tok := l.scan()
 	*l.tokens = append(*l.tokens, tok)
 	return tok
 }
 
+func (l *Lexer) scan() Token {
+	tok := l.scanNext()
+	if l.params != nil && (tok.Type == STRING || tok.Type == TEXT || tok.Type == QTEXT) {
+		tok.Text = substituteParams(tok.Text, l.params)
+	}
+	return tok
+}
+
 func (l *Lexer) readByte() (byte, error) {
 	if l.offset >= len(l.in) {
 		return 0, io.EOF
@@ -271,6 +282,10 @@ func (l *Lexer) scanNext() Token {
 			}
 			l.pos++
 
+			if c == '$' {
+				l.skipPlaceholder()
+				continue
+			}
 			if c == '}' {
 				rr, err := l.readByte()
 				if rr == '}' {
@@ -298,6 +313,11 @@ func (l *Lexer) scanNext() Token {
 		text := make([]byte, 0, endOffset-startOffset)
 		l.offset = startOffset
 		for l.offset < endOffset {
+			if n := placeholderLen(unsafeString(l.in[l.offset:endOffset])); n > 0 {
+				text = append(text, l.in[l.offset:l.offset+n]...)
+				l.offset += n
+				continue
+			}
 			b := l.in[l.offset]
 			l.offset++
 			if b == '}' {
@@ -320,6 +340,9 @@ func (l *Lexer) scanNext() Token {
 
 		// STRING
 		startOffset := l.offset - 1
+		if c == '$' {
+			l.skipPlaceholder()
+		}
 		for {
 			c, err = l.readByte()
 			if err == io.EOF {
@@ -337,6 +360,10 @@ func (l *Lexer) scanNext() Token {
 			}
 			l.pos++
 
+			if c == '$' {
+				l.skipPlaceholder()
+				continue
+			}
 			if !isSTRING(c) {
 				l.unreadByte()
 				l.pos--
@@ -350,6 +377,15 @@ func (l *Lexer) scanNext() Token {
 	}
 }
 
+// skipPlaceholder skips the rest of a variable (${name}) or an expression (${$*10})
+// if the last read "$" starts one.
+func (l *Lexer) skipPlaceholder() {
+	if n := placeholderLen(unsafeString(l.in[l.offset-1:])); n > 0 {
+		l.offset += n - 1
+		l.pos += n - 1
+	}
+}
+
 func (l *Lexer) skipSpace(initr byte) int {
 	posdelta := 0
 	if isSpace(initr) {
@@ -381,3 +417,49 @@ var lexerPool = sync.Pool{
 		return &Lexer{}
 	},
 }
+
+// tokenEnd returns the position just after tok in the input in.
+func tokenEnd(in []byte, tok Token) int {
+	switch tok.Type {
+	case EOF, ERR:
+		return tok.Pos
+	case STRING:
+		// scanned again, since params of a macro change the length of Text
+		i := tok.Pos - 1
+		for first := true; i < len(in); first = false {
+			if n := placeholderLen(unsafeString(in[i:])); n > 0 {
+				i += n
+				continue
+			}
+			if !first && !isSTRING(in[i]) {
+				break
+			}
+			i++
+		}
+		return i + 1
+	case TEXT, QTEXT:
+		closing := byte('}')
+		if tok.Type == QTEXT {
+			closing = in[tok.Pos-1]
+		}
+		for i := tok.Pos; i < len(in); i++ {
+			if tok.Type == TEXT {
+				if n := placeholderLen(unsafeString(in[i:])); n > 0 {
+					i += n - 1
+					continue
+				}
+			}
+			if in[i] != closing {
+				continue
+			}
+			if i+1 < len(in) && in[i+1] == closing {
+				i++ // escaped
+				continue
+			}
+			return i + 2
+		}
+		return len(in) + 1
+	default:
+		return tok.Pos + 1
+	}
+}
@@ -222,6 +222,31 @@ func TestLexerCombo(t *testing.T) {
 			ennet.Token{Type: ennet.EOF},
 		)
 	})
+	t.Run("Variable", func(t *testing.T) {
+		l := input(`a[href=${url}]{${label:x}}}y}+${tag}.a${b.c}$`)
+		test(t, l,
+			ennet.Token{Type: ennet.STRING, Text: "a"},
+			ennet.Token{Type: ennet.ATTRBEGIN},
+			ennet.Token{Type: ennet.STRING, Text: "href"},
+			ennet.Token{Type: ennet.EQ},
+			ennet.Token{Type: ennet.STRING, Text: "${url}"},
+			ennet.Token{Type: ennet.ATTREND},
+			ennet.Token{Type: ennet.TEXT, Text: "${label:x}}y"},
+			ennet.Token{Type: ennet.SIBLING},
+			ennet.Token{Type: ennet.STRING, Text: "${tag}"},
+			ennet.Token{Type: ennet.CLASS},
+			ennet.Token{Type: ennet.STRING, Text: "a${b.c}$"},
+			ennet.Token{Type: ennet.EOF},
+		)
+
+		// not a variable
+		l = input(`a${ITEM$}`)
+		test(t, l,
+			ennet.Token{Type: ennet.STRING, Text: "a$"},
+			ennet.Token{Type: ennet.TEXT, Text: "ITEM$"},
+			ennet.Token{Type: ennet.EOF},
+		)
+	})
 }
 
 func TestLexerEmmetDocumentation(t *testing.T) {
@@ -0,0 +1,184 @@
+package ennet
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LoremCorpus is a source of dummy text for "lorem" and "ipsum".
+//
+// In an abbreviation, an element named lorem or ipsum without attributes and children
+// is replaced by dummy text of 30 words, and loremN (ipsumN) by N words.
+type LoremCorpus struct {
+	// Start begins the first dummy text of an expansion.
+	Start []string
+	// Words are picked at random.
+	Words []string
+
+	// Separator is put between words.
+	Separator string
+	// Terminator ends each sentence.
+	Terminator string
+	// Capitalize makes the first letter of each sentence upper case.
+	Capitalize bool
+}
+
+// latinLorem is the corpus of LatinLorem. It is never modified.
+var latinLorem = LoremCorpus{
+	Start: strings.Fields("lorem ipsum dolor sit amet consectetur adipisicing elit"),
+	Words: strings.Fields(`
+		exercitationem perferendis perspiciatis laborum eveniet sunt iure nam nobis eum cum officiis
+		excepturi odio consectetur quasi aut quisquam vel eligendi itaque non odit tempore quaerat
+		dignissimos facilis neque nihil expedita vitae vero ipsum nisi animi cumque pariatur velit
+		modi natus iusto eaque sequi illo sed ex et voluptatibus tempora veritatis ratione assumenda
+		incidunt nostrum placeat aliquid fuga provident praesentium rem necessitatibus suscipit
+		adipisci quidem possimus voluptas debitis sint accusantium unde sapiente voluptate qui
+		aspernatur laudantium soluta amet quo aliquam saepe culpa libero ipsa dicta reiciendis nesciunt
+		doloribus autem impedit minima maiores repudiandae ipsam obcaecati ullam enim totam delectus
+		ducimus quis voluptates dolores molestiae harum dolorem quia voluptatem molestias magni
+		distinctio omnis illum dolorum voluptatum ea ut earum optio consequatur reprehenderit officia
+		atque laboriosam magnam`),
+	Separator:  " ",
+	Terminator: ".",
+	Capitalize: true,
+}
+
+// japaneseLorem is the corpus of JapaneseLorem. It is never modified.
+var japaneseLorem = LoremCorpus{
+	Start: []string{"吾輩は", "猫である。", "名前は", "まだ", "無い"},
+	Words: []string{
+		"どこで", "生れたか", "とんと", "見当が", "つかぬ", "何でも", "薄暗い", "じめじめした", "所で",
+		"ニャーニャー", "泣いていた", "事だけは", "記憶している", "吾輩は", "ここで", "始めて",
+		"人間という", "ものを", "見た", "しかも", "あとで", "聞くと", "それは", "書生という",
+		"人間中で", "一番", "獰悪な", "種族", "であったそうだ", "この", "書生というのは", "時々",
+		"我々を", "捕えて", "煮て", "食うという", "話である",
+	},
+	Separator:  "",
+	Terminator: "。",
+}
+
+// LatinLorem returns a copy of the default corpus.
+func LatinLorem() LoremCorpus {
+	return latinLorem.clone()
+}
+
+// JapaneseLorem returns a copy of a corpus of Japanese dummy text.
+func JapaneseLorem() LoremCorpus {
+	return japaneseLorem.clone()
+}
+
+func (c LoremCorpus) clone() LoremCorpus {
+	c.Start = slices.Clone(c.Start)
+	c.Words = slices.Clone(c.Words)
+	return c
+}
+
+// WithLorem sets the corpus and the seed of dummy text.
+// The same seed gives the same text.
+// A corpus without Words disables dummy text, so lorem and ipsum are elements.
+// corpus is copied.
+func WithLorem(corpus LoremCorpus, seed uint64) Option {
+	corpus = corpus.clone()
+	return func(o *options) {
+		o.lorem = &corpus
+		o.loremSeed = seed
+	}
+}
+
+const defaultLoremWords = 30
+
+// loremCount returns the count of words if n is a dummy text.
+func (r *renderer) loremCount(n *Node) (int, bool) {
+	if n.Type != Element || len(n.Attributes) > 0 || n.FirstChild != nil {
+		return 0, false
+	}
+	if c := r.opts.lorem; c != nil && len(c.Words) == 0 {
+		return 0, false
+	}
+
+	var num string
+	if s, found := strings.CutPrefix(n.Data, "lorem"); found {
+		num = s
+	} else if s, found := strings.CutPrefix(n.Data, "ipsum"); found {
+		num = s
+	} else {
+		return 0, false
+	}
+
+	if num == "" {
+		return defaultLoremWords, true
+	}
+	count, err := strconv.Atoi(num)
+	if err != nil || count < 0 {
+		return 0, false
+	}
+	return count, true
+}
+
+// lorem writes count words of dummy text for n.
+// Each call gives different text, in the same order for the same seed.
+//
+// count is limited like a multiplication by WithMaxMul, and words are written one by one
+// up to WithMaxOutput.
+func (r *renderer) lorem(n *Node, count int) {
+	if !r.checkMul(n, count, ErrLoremLimit) {
+		return
+	}
+	if max := r.opts.maxOutput; max > 0 && count > max {
+		r.err = ErrOutputLimit // each word takes a byte at least
+		return
+	}
+
+	corpus := r.opts.lorem
+	if corpus == nil {
+		corpus = &latinLorem
+	}
+	if r.rng == nil {
+		r.rng = rand.New(rand.NewPCG(r.opts.loremSeed, 0))
+	}
+
+	start := 0 // words of corpus.Start
+	if !r.loremStarted {
+		r.loremStarted = true
+		start = min(count, len(corpus.Start))
+	}
+
+	sentence := 0 // words left in the current sentence
+	for i := range count {
+		word := ""
+		if i < start {
+			word = corpus.Start[i]
+		} else {
+			word = corpus.Words[r.rng.IntN(len(corpus.Words))]
+		}
+
+		if sentence == 0 {
+			sentence = 5 + r.rng.IntN(6)
+			if corpus.Capitalize && word != "" {
+				first, size := utf8.DecodeRuneInString(word)
+				word = string(unicode.ToUpper(first)) + word[size:]
+			}
+		}
+		if i > 0 {
+			r.w.WriteString(corpus.Separator)
+		}
+		r.writeString(word, escText)
+
+		sentence--
+		if sentence == 0 || i == count-1 {
+			if !strings.HasSuffix(word, corpus.Terminator) {
+				r.writeString(corpus.Terminator, escText)
+			}
+			sentence = 0
+		}
+
+		if max := r.opts.maxOutput; max > 0 && r.w.Len() > max {
+			r.err = ErrOutputLimit
+			return
+		}
+	}
+}
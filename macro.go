@@ -0,0 +1,262 @@
+package ennet
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Macros are abbreviations with parameters.
+//
+// A macro is defined as
+//
+//	card(title, body) = div.card>(h2{$title}+div.card-body{$body})
+//
+// and called as card("Hi", "text")*3.
+// Calls are expanded at parse time, as groups.
+// An argument is a quoted text or a string, and $name in the body is replaced by it.
+// Arguments are literal: "$" in them is not a numbering or a variable.
+type Macros struct {
+	defs map[string]*macro
+}
+
+type macro struct {
+	name   string
+	params []string
+	body   string
+}
+
+// Define adds a macro definition "name(params) = body".
+// A macro of the same name is replaced.
+func (m *Macros) Define(def string) error {
+	head, body, found := strings.Cut(def, "=")
+	body = strings.TrimSpace(body)
+	if !found || body == "" {
+		return errors.New("macro body following = is required")
+	}
+
+	head = strings.TrimSpace(head)
+	name, params, found := strings.Cut(head, "(")
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsFunc(name, func(r rune) bool { return r > 0x7f || !isSTRING(byte(r)) }) {
+		return errors.New("invalid macro name " + strconv.Quote(name))
+	}
+	params, closed := strings.CutSuffix(params, ")")
+	if !found || !closed {
+		return errors.New("( params ) is required after macro name " + name)
+	}
+
+	mac := &macro{name: name, body: body}
+	if params = strings.TrimSpace(params); params != "" {
+		for _, param := range strings.Split(params, ",") {
+			param = strings.TrimSpace(param)
+			if !isParamName(param) || slices.Contains(mac.params, param) {
+				return errors.New("invalid param " + strconv.Quote(param) + " of macro " + name)
+			}
+			mac.params = append(mac.params, param)
+		}
+	}
+
+	if m.defs == nil {
+		m.defs = make(map[string]*macro)
+	}
+	m.defs[name] = mac
+	return nil
+}
+
+// Parse parses b like Parse, expanding calls of the macros.
+func (m *Macros) Parse(b []byte, builder Builder) error {
+	return parse(b, builder, m, nil)
+}
+
+// WithMacros makes Expand expand calls of m.
+func WithMacros(m *Macros) Option {
+	return func(o *options) {
+		o.macros = m
+	}
+}
+
+func isParamName(s string) bool {
+	if !isVariableNameStart(s, 0) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isVariableNameByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// macroCall parses name(args) if it is a call of a macro.
+func (p *Parser) macroCall() bool {
+	if p.macros == nil {
+		return false
+	}
+
+	name := p.lexer.Next()
+	open := p.lexer.Peek()
+	mac := p.macros.defs[name.Text]
+	if name.Type != STRING || open.Type != GROUPBEGIN || open.Pos != tokenEnd(p.lexer.in, name) || mac == nil {
+		p.lexer.Back()
+		return false
+	}
+	p.lexer.Next()
+
+	args, closing := p.macroArgs()
+	if len(args) != len(mac.params) {
+		p.fail(name, fmt.Errorf("macro %s takes %d arguments, not %d", mac.name, len(mac.params), len(args)))
+	}
+	if slices.Contains(p.calls, mac.name) {
+		p.fail(name, errors.New("recursive macro "+mac.name))
+	}
+
+	p.at(name, closing)
+	if err := p.builder.GroupBegin(); err != nil {
+		p.fail(name, err)
+	}
+	p.expandMacro(mac, args, name, closing)
+	p.at(name, closing)
+	if err := p.builder.GroupEnd(); err != nil {
+		p.fail(closing, err)
+	}
+
+	if p.lexer.Peek().Type == MULT {
+		p.multiplication()
+	}
+	return true
+}
+
+// macroArgs parses arguments up to ")", and returns them and ")".
+func (p *Parser) macroArgs() ([]string, Token) {
+	var args []string
+	var arg strings.Builder
+	hasArg := false
+	prevEnd := 0
+	for {
+		tok := p.lexer.Next()
+		switch tok.Type {
+		case GROUPEND:
+			if hasArg || len(args) > 0 {
+				args = append(args, arg.String())
+			}
+			return args, tok
+
+		case STRING, QTEXT, TEXT:
+			if hasArg && arg.Len() > 0 && tok.Pos > prevEnd {
+				arg.WriteByte(' ') // hello world
+			}
+			hasArg = true
+
+			text := tok.Text
+			if p.lexer.params != nil {
+				text = strings.ReplaceAll(text, `\$`, "$") // unescape arguments substituted in a body
+			}
+			for tok.Type == STRING {
+				before, after, found := strings.Cut(text, ",")
+				arg.WriteString(before)
+				if !found {
+					break
+				}
+				args = append(args, arg.String())
+				arg.Reset()
+				text = after
+			}
+			if tok.Type != STRING {
+				arg.WriteString(text)
+			}
+
+			prevEnd = tokenEnd(p.lexer.in, tok)
+
+		default:
+			p.fail(tok, errors.New(") is required in the end of macro arguments"))
+		}
+	}
+}
+
+// expandMacro parses the body of mac with args.
+// Builder calls and errors are located at the call, from first to last.
+func (p *Parser) expandMacro(mac *macro, args []string, first, last Token) {
+	sub := Parser{
+		lexer:     NewLexer([]byte(mac.body)),
+		builder:   p.builder,
+		pb:        p.pb,
+		macros:    p.macros,
+		calls:     append(slices.Clip(p.calls), mac.name),
+		fragments: p.fragments,
+		includes:  p.includes,
+		span:      p.span,
+	}
+	if sub.span == [2]int{} {
+		sub.span = [2]int{first.Pos, tokenEnd(p.lexer.in, last)}
+	}
+	sub.lexer.params = make(map[string]string, len(args))
+	for i, param := range mac.params {
+		sub.lexer.params[param] = args[i]
+	}
+
+	if perr := parseNested(&sub); perr != nil {
+		panic(&ParseError{
+			Pos: first.Pos,
+			End: tokenEnd(p.lexer.in, last),
+			Err: fmt.Errorf("macro %s: %w", mac.name, perr.Err),
+		})
+	}
+}
+
+// parseNested parses the whole input of sub, which shares the builder with its parent.
+func parseNested(sub *Parser) (perr *ParseError) {
+	defer func() {
+		sub.lexer.Close()
+		if r := recover(); r != nil {
+			var ok bool
+			if perr, ok = r.(*ParseError); !ok {
+				panic(r)
+			}
+		}
+	}()
+
+	if !sub.abbreviation() {
+		sub.fail(sub.lexer.Peek(), errors.New("A group or element is required"))
+	}
+	if tok := sub.lexer.Next(); tok.Type == ERR {
+		sub.fail(tok, errors.New("parsing failed because of "+tok.String()))
+	} else if tok.Type != EOF {
+		sub.fail(tok, errors.New("parsing failed because of extra "+tok.String()))
+	}
+	return nil
+}
+
+// substituteParams replaces $name in s by params[name], escaping "$" in it (\$).
+func substituteParams(s string, params map[string]string) string {
+	if strings.IndexByte(s, '$') == -1 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || !isVariableNameStart(s, i+1) || i > 0 && s[i-1] == '\\' {
+			continue
+		}
+		end := i + 1
+		for end < len(s) && isVariableNameByte(s[end]) {
+			end++
+		}
+		value, found := params[s[i+1:end]]
+		if !found {
+			continue
+		}
+		b.WriteString(s[last:i])
+		b.WriteString(strings.ReplaceAll(value, "$", `\$`))
+		last = end
+		i = end - 1
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
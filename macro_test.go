@@ -0,0 +1,112 @@
+package ennet_test
+
+import (
+	"testing"
+
+	"github.com/shu-go/ennet"
+	"github.com/shu-go/gotwant"
+)
+
+func TestMacros(t *testing.T) {
+	var m ennet.Macros
+	gotwant.TestError(t, m.Define(`card(title, body) = div.card>(h2{$title}+div.card-body{$body})`), nil)
+	gotwant.TestError(t, m.Define(`deck(n) = section.deck$>card("Deck $n", body)`), nil)
+	gotwant.TestError(t, m.Define(`hr() = hr.sep`), nil)
+	gotwant.TestError(t, m.Define(`ping(x) = a>pong($x)`), nil)
+	gotwant.TestError(t, m.Define(`pong(x) = b>ping($x)`), nil)
+	gotwant.TestError(t, m.Define(`bad(x) = a{$x`), nil)
+	gotwant.TestError(t, m.Define(`price(v) = span{$v}`), nil)
+	gotwant.TestError(t, m.Define(`tag(v) = price($v)`), nil)
+	gotwant.TestError(t, m.Define(`list(name) = ul>li*@$name.items{${.}}`), nil)
+
+	t.Run("Call", func(t *testing.T) {
+		s, err := ennet.Expand(`card("Hi", "text")*2`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<div class="card"><h2>Hi</h2><div class="card-body">text</div></div><div class="card"><h2>Hi</h2><div class="card-body">text</div></div>`)
+
+		s, err = ennet.Expand(`ul>li>card(Hello world, "a, b}")+hr()`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<ul><li><div class="card"><h2>Hello world</h2><div class="card-body">a, b}</div></div><hr class="sep" /></li></ul>`)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		s, err := ennet.Expand(`deck(1)*2`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<section class="deck1"><div class="card"><h2>Deck 1</h2><div class="card-body">body</div></div></section><section class="deck2"><div class="card"><h2>Deck 1</h2><div class="card-body">body</div></div></section>`)
+	})
+
+	t.Run("Literal", func(t *testing.T) {
+		s, err := ennet.Expand(`ul>price("$")*3`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<ul><span>$</span><span>$</span><span>$</span></ul>`)
+
+		s, err = ennet.Expand(`price("${x}")+price("$$@3")`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<span>${x}</span><span>$$@3</span>`)
+
+		s, err = ennet.Expand(`tag("$ \\$")`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<span>$ \\$</span>`)
+
+		s, err = ennet.Expand(`p{\$$}*2`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p>$1</p><p>$2</p>`)
+	})
+
+	t.Run("Pos", func(t *testing.T) {
+		var sm []ennet.SourceMapping
+		_, err := ennet.Expand(`p>hr()`, ennet.WithMacros(&m), ennet.WithSourceMap(&sm))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, sm[len(sm)-1], ennet.SourceMapping{OutStart: 3, OutEnd: 21, Pos: 3, End: 7})
+
+		// $name is longer or shorter than its argument
+		s, err := ennet.Expand(`list(fruits)`, ennet.WithMacros(&m), ennet.WithData(map[string]any{
+			"fruits": map[string]any{"items": []string{"apple", "kiwi"}},
+		}))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<ul><li>apple</li><li>kiwi</li></ul>`)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := ennet.Expand(`p>card(1)`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, "macro card takes 2 arguments, not 1")
+		perr := err.(*ennet.ParseError)
+		gotwant.Test(t, perr.Pos, 3)
+
+		_, err = ennet.Expand(`ping(1)`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, "macro ping: macro pong: recursive macro ping")
+
+		_, err = ennet.Expand(`p+bad(1)`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, "macro bad: parsing failed because of sudden EOF")
+		perr = err.(*ennet.ParseError)
+		gotwant.Test(t, perr.Pos, 3)
+		gotwant.Test(t, perr.End, 9)
+
+		_, err = ennet.Expand(`p+list("a long name")`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, "macro list: a data name following *@ is required")
+		perr = err.(*ennet.ParseError)
+		gotwant.Test(t, perr.Pos, 3)
+		gotwant.Test(t, perr.End, 22)
+
+		_, err = ennet.Expand(`p+list(xs)>b`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, ennet.ErrUndefinedVariable)
+		perr = err.(*ennet.ParseError)
+		gotwant.Test(t, perr.Pos, 3)
+		gotwant.Test(t, perr.End, 11)
+
+		_, err = ennet.Expand(`card("a", "b"`, ennet.WithMacros(&m))
+		gotwant.TestError(t, err, ") is required in the end of macro arguments")
+
+		_, err = ennet.Expand(`card("a", "b")`)
+		gotwant.TestError(t, err, "parsing failed because of extra (")
+	})
+
+	t.Run("Define", func(t *testing.T) {
+		var m ennet.Macros
+		gotwant.TestError(t, m.Define(`card(title)`), "macro body following = is required")
+		gotwant.TestError(t, m.Define(`card = p`), "( params ) is required")
+		gotwant.TestError(t, m.Define(`c.d() = p`), "invalid macro name")
+		gotwant.TestError(t, m.Define(`card(a, a) = p`), `invalid param "a"`)
+		gotwant.TestError(t, m.Define(`card(1) = p`), `invalid param "1"`)
+	})
+}
@@ -0,0 +1,74 @@
+package ennet
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// MergeFunc merges the value of a repeated attribute name into the old value,
+// such as a#x#y, a.b.c or a[href=x href=y].
+type MergeFunc func(name, old, value string) (string, error)
+
+// MergeTokens merges space-separated tokens, without duplicates.
+// It is the default for class.
+func MergeTokens(name, old, value string) (string, error) {
+	tokens := strings.Fields(old)
+	for _, token := range strings.Fields(value) {
+		if !slices.Contains(tokens, token) {
+			tokens = append(tokens, token)
+		}
+	}
+	return strings.Join(tokens, " "), nil
+}
+
+// MergeLast takes the last value. It is the default except for class.
+func MergeLast(name, old, value string) (string, error) {
+	return value, nil
+}
+
+// MergeError fails if the values differ.
+func MergeError(name, old, value string) (string, error) {
+	if old != value {
+		return "", errors.New("conflicting values of attribute " + name)
+	}
+	return old, nil
+}
+
+// MergeJoin returns a MergeFunc that joins the values with sep, such as MergeJoin(";") for style.
+func MergeJoin(sep string) MergeFunc {
+	return func(name, old, value string) (string, error) {
+		if old == "" {
+			return value, nil
+		}
+		if value == "" {
+			return old, nil
+		}
+		return old + sep + value, nil
+	}
+}
+
+// WithMerge sets how repeated attributes name are merged.
+// name "" sets the default for attributes without their own MergeFunc.
+func WithMerge(name string, f MergeFunc) Option {
+	return func(o *options) {
+		if o.merges == nil {
+			o.merges = make(map[string]MergeFunc)
+		}
+		o.merges[name] = f
+	}
+}
+
+// merge returns the MergeFunc of the attribute name.
+func (nb *NodeBuilder) merge(name string) MergeFunc {
+	if f := nb.Merges[name]; f != nil {
+		return f
+	}
+	if name == "class" {
+		return MergeTokens
+	}
+	if f := nb.Merges[""]; f != nil {
+		return f
+	}
+	return MergeLast
+}
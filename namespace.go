@@ -0,0 +1,177 @@
+package ennet
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// WithNamespace maps prefix to the namespace uri.
+// The prefix "" is the default namespace, of elements without a prefix.
+//
+// With namespaces, Expand declares xmlns:prefix on each outermost element that uses prefix
+// in its name or attribute names, unless it is already declared by an xmlns attribute.
+// Element and attribute names must be QNames, and prefixes must be declared.
+func WithNamespace(prefix, uri string) Option {
+	return func(o *options) {
+		if o.namespaces == nil {
+			o.namespaces = make(map[string]string)
+		}
+		o.namespaces[prefix] = uri
+	}
+}
+
+// DeclareNamespace declares prefix ("" for the default namespace) as uri for the whole tree,
+// so that Node.NamespaceURI resolves it without xmlns attributes.
+// The declarations are the xmlns attributes of Root, which is not written by Expand.
+func (nb *NodeBuilder) DeclareNamespace(prefix, uri string) {
+	name := "xmlns"
+	if prefix != "" {
+		name += ":" + prefix
+	}
+	for i := range nb.Root.Attributes {
+		if nb.Root.Attributes[i].Name == name {
+			nb.Root.Attributes[i].Value = uri
+			return
+		}
+	}
+	nb.Root.Attributes = append(nb.Root.Attributes, Attribute{Name: name, Value: uri})
+}
+
+// splitQName splits name into prefix and local name (soap and Envelope of soap:Envelope).
+// prefix is "" if name has no valid prefix.
+func splitQName(name string) (prefix, local string) {
+	prefix, local, found := strings.Cut(name, ":")
+	if !found || !isNCName(prefix) {
+		return "", name
+	}
+	return prefix, local
+}
+
+func isQName(name string) bool {
+	prefix, local, found := strings.Cut(name, ":")
+	if !found {
+		return isNCName(name)
+	}
+	return isNCName(prefix) && isNCName(local)
+}
+
+// isNCName reports whether s is a name without ":".
+// Non-ASCII characters are accepted as name characters.
+func isNCName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 0x80, c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && (c == '-' || c == '.' || '0' <= c && c <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// namespaceDecl returns the prefix declared by the attribute name (xmlns or xmlns:prefix).
+func namespaceDecl(name string) (prefix string, ok bool) {
+	if name == "xmlns" {
+		return "", true
+	}
+	return strings.CutPrefix(name, "xmlns:")
+}
+
+// declareNamespaces adds xmlns attributes of WithNamespace to the outermost elements
+// that use the prefixes. declared is the prefixes in scope.
+func (r *renderer) declareNamespaces(n *Node, declared []string) {
+	if r.err != nil {
+		return
+	}
+
+	if n.Type == Element {
+		if _, ok := r.loremCount(n); ok {
+			return
+		}
+
+		for _, attr := range n.Attributes {
+			if prefix, ok := namespaceDecl(attr.Name); ok {
+				declared = append(slices.Clip(declared), prefix)
+			}
+		}
+
+		var decls []Attribute
+		declare := func(prefix string) {
+			if prefix == "xml" || slices.Contains(declared, prefix) {
+				return
+			}
+			uri, found := r.opts.namespaces[prefix]
+			if !found {
+				if prefix != "" {
+					r.err = &ParseError{Pos: n.Pos, End: n.End, Err: errors.New("undeclared namespace prefix " + prefix)}
+				}
+				return
+			}
+			name := "xmlns"
+			if prefix != "" {
+				name += ":" + prefix
+			}
+			decls = append(decls, Attribute{Name: name, Value: uri})
+			declared = append(slices.Clip(declared), prefix)
+		}
+
+		prefix, _ := splitQName(n.Data)
+		declare(prefix)
+		for _, attr := range n.Attributes {
+			if _, ok := namespaceDecl(attr.Name); ok {
+				continue
+			}
+			if prefix, _ := splitQName(attr.Name); prefix != "" {
+				declare(prefix)
+			}
+		}
+		if len(decls) > 0 {
+			n.Attributes = slices.Insert(n.Attributes, 0, decls...)
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.declareNamespaces(c, declared)
+	}
+}
+
+// checkName fails if the name written from start by the template templ is invalid,
+// that is not a QName with namespaces, or not a name if it contains a variable.
+func (r *renderer) checkName(n *Node, templ string, start int) {
+	if r.err != nil {
+		return
+	}
+	name := r.w.Bytes()[start:]
+	switch {
+	case r.opts.namespaces != nil:
+		if !isQName(string(name)) {
+			r.err = &ParseError{Pos: n.Pos, End: n.End, Err: errors.New("invalid QName " + string(name))}
+		}
+	case strings.Contains(templ, "${"):
+		if !isName(name) {
+			r.err = &ParseError{Pos: n.Pos, End: n.End, Err: errors.New("invalid name " + strconv.Quote(string(name)))}
+		}
+	}
+}
+
+// isName reports whether name has no character that ends a name in a tag.
+func isName(name []byte) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c <= ' ', c == 0x7f:
+			return false
+		case c == '"', c == '\'', c == '<', c == '>', c == '/', c == '=', c == '&':
+			return false
+		}
+	}
+	return true
+}
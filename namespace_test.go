@@ -0,0 +1,102 @@
+package ennet_test
+
+import (
+	"testing"
+
+	"github.com/shu-go/ennet"
+	"github.com/shu-go/gotwant"
+)
+
+func TestNamespace(t *testing.T) {
+	soap := ennet.WithNamespace("soap", "http://schemas.xmlsoap.org/soap/envelope/")
+	xlink := ennet.WithNamespace("xlink", "http://www.w3.org/1999/xlink")
+	svg := ennet.WithNamespace("", "http://www.w3.org/2000/svg")
+
+	t.Run("Outermost", func(t *testing.T) {
+		s, err := ennet.Expand(`soap:Envelope>soap:Body>GetPrice`, soap)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><GetPrice /></soap:Body></soap:Envelope>`)
+
+		s, err = ennet.Expand(`(soap:Header+soap:Body)*2`, soap)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<soap:Header xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" /><soap:Body xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" />`+
+			`<soap:Header xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" /><soap:Body xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" />`)
+	})
+
+	t.Run("Attributes", func(t *testing.T) {
+		s, err := ennet.Expand(`svg>use[xlink:href="#a"]+use[xlink:href="#b"]`, svg, xlink)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<svg xmlns="http://www.w3.org/2000/svg"><use xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="#a" /><use xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="#b" /></svg>`)
+
+		s, err = ennet.Expand(`p[xml:lang=ja]`, soap)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<p xml:lang="ja" />`)
+	})
+
+	t.Run("Declared", func(t *testing.T) {
+		s, err := ennet.Expand(`env[xmlns:soap=urn:x]>soap:Body`, soap)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<env xmlns:soap="urn:x"><soap:Body /></env>`)
+
+		s, err = ennet.Expand(`soap:Body`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<soap:Body />`)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := ennet.Expand(`soap:Body>m:GetPrice`, soap)
+		gotwant.TestError(t, err, "undeclared namespace prefix m")
+
+		_, err = ennet.Expand(`a[x"=1]`, soap)
+		gotwant.TestError(t, err, `invalid QName x"`)
+
+		_, err = ennet.Expand(`soap:a:b`, soap)
+		gotwant.TestError(t, err, `invalid QName soap:a:b`)
+
+		_, err = ennet.Expand(`a[x"=1]`)
+		gotwant.TestError(t, err, nil)
+	})
+
+	t.Run("Node", func(t *testing.T) {
+		nb := ennet.NewNodeBuilder(nil)
+		err := ennet.Parse([]byte(`soap:Envelope[xmlns:soap=urn:soap xmlns=urn:default]>soap:Body>GetPrice`), &nb)
+		gotwant.TestError(t, err, nil)
+
+		env := nb.Root.FirstChild
+		gotwant.Test(t, env.Prefix(), "soap")
+		gotwant.Test(t, env.LocalName(), "Envelope")
+		gotwant.Test(t, env.NamespaceURI(), "urn:soap")
+
+		body := env.FirstChild
+		gotwant.Test(t, body.LocalName(), "Body")
+		gotwant.Test(t, body.NamespaceURI(), "urn:soap")
+
+		price := body.FirstChild
+		gotwant.Test(t, price.Prefix(), "")
+		gotwant.Test(t, price.LocalName(), "GetPrice")
+		gotwant.Test(t, price.NamespaceURI(), "urn:default")
+	})
+
+	t.Run("Declared", func(t *testing.T) {
+		nb := ennet.NewNodeBuilder(nil)
+		nb.DeclareNamespace("soap", "urn:soap")
+		nb.DeclareNamespace("", "urn:default")
+		err := ennet.Parse([]byte(`soap:Envelope>soap:Body>GetPrice+m:x+p[xmlns:soap=urn:other]>soap:Fault`), &nb)
+		gotwant.TestError(t, err, nil)
+
+		env := nb.Root.FirstChild
+		gotwant.Test(t, env.NamespaceURI(), "urn:soap")
+		body := env.FirstChild
+		gotwant.Test(t, body.NamespaceURI(), "urn:soap")
+		price := body.FirstChild
+		gotwant.Test(t, price.NamespaceURI(), "urn:default")
+		gotwant.Test(t, price.NextSibling.NamespaceURI(), "")
+		fault := price.NextSibling.NextSibling.FirstChild
+		gotwant.Test(t, fault.NamespaceURI(), "urn:other")
+
+		nb = ennet.NewNodeBuilder(nil)
+		err = ennet.ParseWith([]byte(`soap:Body`), &nb, soap)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, nb.Root.FirstChild.NamespaceURI(), "http://schemas.xmlsoap.org/soap/envelope/")
+	})
+}
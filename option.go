@@ -0,0 +1,135 @@
+package ennet
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Option configures Expand.
+type Option func(*options)
+
+type options struct {
+	sourceMap *[]SourceMapping
+
+	mode      Mode
+	indent    string
+	escape    bool
+	attrOrder AttrOrder
+	merges    map[string]MergeFunc
+	boolAttrs []string
+
+	defaultAttrs    map[string][]Attribute
+	defaultAttrsSet bool
+
+	maxMul    int
+	maxOutput int
+
+	data      any
+	macros    *Macros
+	fragments FragmentFunc
+
+	namespaces map[string]string
+
+	xmlDecl string
+	doctype string
+
+	lorem     *LoremCorpus
+	loremSeed uint64
+}
+
+// Mode is the output markup language.
+type Mode uint8
+
+const (
+	// XML writes empty elements as empty-element tags (<a />).
+	XML Mode = iota
+	// HTML writes void elements without "/" (<br>), and others with end tags (<a></a>).
+	HTML
+	// XHTML writes void elements as empty-element tags (<br />), and others with end tags (<a></a>).
+	XHTML
+)
+
+var mode2String = map[Mode]string{
+	XML:   "xml",
+	HTML:  "html",
+	XHTML: "xhtml",
+}
+
+func (m Mode) String() string {
+	if s, found := mode2String[m]; found {
+		return s
+	}
+	return "???"
+}
+
+// ParseMode returns the Mode named s ("xml", "html" or "xhtml").
+func ParseMode(s string) (Mode, error) {
+	for m, name := range mode2String {
+		if strings.EqualFold(s, name) {
+			return m, nil
+		}
+	}
+	return XML, errors.New("unknown mode " + strconv.Quote(s))
+}
+
+var noOptions options
+
+// WithSourceMap makes Expand append SourceMappings to m.
+func WithSourceMap(m *[]SourceMapping) Option {
+	return func(o *options) {
+		o.sourceMap = m
+	}
+}
+
+// WithMode sets the output markup language. The default is XML.
+func WithMode(m Mode) Option {
+	return func(o *options) {
+		o.mode = m
+	}
+}
+
+// WithIndent puts child elements on their own lines, indented by indent.
+// Elements that contain only texts stay on one line.
+func WithIndent(indent string) Option {
+	return func(o *options) {
+		o.indent = indent
+	}
+}
+
+// WithEscape escapes &, <, > in texts and attribute values, and " in attribute values.
+//
+// Without it, texts are written as is and " in attribute values is written as \".
+func WithEscape(escape bool) Option {
+	return func(o *options) {
+		o.escape = escape
+	}
+}
+
+// WithBooleanAttributes adds names to the boolean attributes of HTML (checked, disabled, ...).
+//
+// A boolean attribute without a value ([disabled]) is written without a value in HTML mode (<input disabled>),
+// and with its name as the value in XML and XHTML modes (<input disabled="disabled" />).
+func WithBooleanAttributes(names ...string) Option {
+	return func(o *options) {
+		o.boolAttrs = append(o.boolAttrs, names...)
+	}
+}
+
+// WithMaxMul limits the copies of each node by multiplications,
+// that is the product of the counts of the multiplications enclosing it ((a*10)*10 makes 100 copies of a).
+// Expand fails with ErrMulLimit if they exceed max.
+// The words of dummy text (loremN) are limited in the same way, with ErrLoremLimit.
+func WithMaxMul(max int) Option {
+	return func(o *options) {
+		o.maxMul = max
+	}
+}
+
+// WithMaxOutput limits the size of the output in bytes.
+// Expand fails with ErrOutputLimit if the output exceeds max.
+func WithMaxOutput(max int) Option {
+	return func(o *options) {
+		o.maxOutput = max
+	}
+}
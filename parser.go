@@ -3,6 +3,7 @@ package ennet
 import (
 	"errors"
 	"strconv"
+	"strings"
 )
 
 /*
@@ -14,27 +15,118 @@ EBNF:
 	class = ".", STRING;
 
 	tag-element = STRING, { id | class | attr-list }, [ TEXT ];
-	multiplication = "*", NUMBER;
+	multiplication = "*", ( NUMBER | "@", NAME );
 
-	element = ( tag-element | TEXT ), [multiplication];
+	markup = ( "!--" | "!cdata" | "?", NAME ), TEXT;
+
+	element = ( tag-element, [multiplication, [TEXT]] ) | ( ( TEXT | markup ), [multiplication] );
 
 	group = "(", abbreviation, ")", [multiplication];
 
 	operator = CHILD | SIBLING | repeatable-operator;
 	repeatable-operator = CLIMBUP, {CLIMBUP}
 
-	abbreviation = (group | element), [operator, abbreviation]
+	macro-call = NAME, "(", [ argument, { ",", argument } ], ")", [multiplication];
+	include = "@", NAME, [multiplication];
+
+	abbreviation = (group | include | macro-call | element), [operator, abbreviation]
 */
 type Parser struct {
 	lexer   *Lexer
 	builder Builder
+	pb      PosBuilder // builder, if it is a PosBuilder
+
+	// for macros and fragments
+	macros    *Macros
+	calls     []string // names of the macros being expanded
+	fragments FragmentFunc
+	includes  []string // names of the fragments being included
+	span      [2]int   // the call or reference that every builder call is located at, if not zero
+
+	// for ParseTolerant
+	depth int
+	diags []*ParseError
+}
+
+// ParseError is an error with the range of the abbreviation that caused it.
+// Pos and End are 1-based like Token.Pos, and End is exclusive.
+type ParseError struct {
+	Pos, End int
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error() + " at " + strconv.Itoa(e.Pos)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (p *Parser) errorAt(tok Token, err error) *ParseError {
+	return &ParseError{
+		Pos: tok.Pos,
+		End: tokenEnd(p.lexer.in, tok),
+		Err: err,
+	}
+}
+
+// at tells the PosBuilder that the next call comes from first to last.
+func (p *Parser) at(first, last Token) {
+	if p.pb != nil && p.span != [2]int{} {
+		p.pb.Pos(p.span[0], p.span[1])
+	} else if p.pb != nil {
+		p.pb.Pos(first.Pos, tokenEnd(p.lexer.in, last))
+	}
+}
+
+// checkPlaceholders fails at the first invalid placeholder in tok.
+func (p *Parser) checkPlaceholders(tok Token) {
+	start, end, err := checkTemplate(tok.Text)
+	if err == nil {
+		return
+	}
+
+	pos := tok.Pos
+	if tok.Type == TEXT || tok.Type == QTEXT {
+		pos++ // { or "
+	}
+	panic(&ParseError{Pos: pos + start, End: pos + end, Err: err})
 }
 
-func Parse(b []byte, builder Builder) (parseError error) {
+// fail panics with err located at tok.
+func (p *Parser) fail(tok Token, err error) {
+	panic(p.errorAt(tok, err))
+}
+
+func Parse(b []byte, builder Builder) error {
+	return parse(b, builder, nil, nil)
+}
+
+// ParseWith parses b like Parse, expanding the macros (WithMacros) and fragments (WithFragments) of opts.
+// If builder is a *NodeBuilder, the namespaces of WithNamespace are declared on it.
+// The other options are ignored.
+func ParseWith(b []byte, builder Builder, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if nb, ok := builder.(*NodeBuilder); ok {
+		for prefix, uri := range o.namespaces {
+			nb.DeclareNamespace(prefix, uri)
+		}
+	}
+	return parse(b, builder, o.macros, o.fragments)
+}
+
+func parse(b []byte, builder Builder, macros *Macros, fragments FragmentFunc) (parseError error) {
 	p := Parser{
-		lexer:   NewLexer(b),
-		builder: builder,
+		lexer:     NewLexer(b),
+		builder:   builder,
+		macros:    macros,
+		fragments: fragments,
 	}
+	p.pb, _ = builder.(PosBuilder)
 
 	defer func() {
 		if err, ok := recover().(error); ok {
@@ -57,18 +149,159 @@ func Parse(b []byte, builder Builder) (parseError error) {
 
 	tok := p.lexer.Next()
 	if tok.Type == ERR {
-		return errors.New("parsing failed because of " + tok.String() + strconv.Itoa(tok.Pos))
+		return p.errorAt(tok, errors.New("parsing failed because of "+tok.String()))
 	} else if tok.Type != EOF {
-		return errors.New("parsing failed because of extra " + tok.String() + strconv.Itoa(tok.Pos))
+		return p.errorAt(tok, errors.New("parsing failed because of extra "+tok.String()))
 	}
 	if !result {
-		p.lexer.Close()
-		return errors.New("parse error")
+		return p.errorAt(tok, errors.New("parse error"))
 	}
 
 	return nil
 }
 
+// ParseTolerant parses b like Parse, but does not stop at the first error.
+//
+// After an error, it skips to the next operator or closing bracket and goes on,
+// so that builder gets a best-effort tree.
+// It returns all errors found.
+func ParseTolerant(b []byte, builder Builder) []*ParseError {
+	p := Parser{
+		lexer:   NewLexer(b),
+		builder: builder,
+	}
+	p.pb, _ = builder.(PosBuilder)
+	defer p.lexer.Close()
+
+	expectTerm := true
+	for {
+		t := p.lexer.Peek()
+		switch t.Type {
+		case EOF:
+			if expectTerm {
+				p.diag(t, errors.New("A group or element is required"))
+			}
+			for ; p.depth > 0; p.depth-- {
+				if n := len(p.diags); n == 0 || p.diags[n-1].Pos != t.Pos {
+					p.diag(t, errors.New(") is required in the end of a group"))
+				}
+				p.recovering(func() bool {
+					if err := p.builder.GroupEnd(); err != nil {
+						p.fail(t, err)
+					}
+					return true
+				})
+			}
+			return p.diags
+
+		case CHILD, SIBLING, CLIMBUP:
+			if expectTerm {
+				p.diag(t, errors.New("A group or element is required"))
+			}
+			p.recovering(p.operator)
+			expectTerm = true
+
+		case GROUPEND:
+			p.lexer.Next()
+			if p.depth == 0 {
+				p.diag(t, errors.New("parsing failed because of extra "+t.String()))
+			} else {
+				p.depth--
+				p.recovering(func() bool {
+					p.at(t, t)
+					if err := p.builder.GroupEnd(); err != nil {
+						p.fail(t, err)
+					}
+					if p.lexer.Peek().Type == MULT {
+						p.multiplication()
+					}
+					return true
+				})
+			}
+			expectTerm = false
+
+		case GROUPBEGIN, STRING, TEXT:
+			if !expectTerm {
+				p.diag(t, errors.New("parsing failed because of extra "+t.String()))
+				p.skip()
+				continue
+			}
+			if !p.recovering(p.abbreviation) {
+				p.diag(p.lexer.Peek(), errors.New("A group or element is required"))
+			}
+			expectTerm = false
+
+		default:
+			p.lexer.Next()
+			p.diag(t, errors.New("parsing failed because of "+t.String()))
+			p.skip()
+		}
+	}
+}
+
+func (p *Parser) diag(tok Token, err error) {
+	p.diags = append(p.diags, p.errorAt(tok, err))
+}
+
+// recovering runs f, and on a failure records it and resynchronizes.
+func (p *Parser) recovering(f func() bool) (ok bool) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		perr, isParseError := r.(*ParseError)
+		if !isParseError {
+			panic(r)
+		}
+		p.diags = append(p.diags, perr)
+
+		// give back the offending token if it can resynchronize
+		if p.lexer.scanpos > 0 {
+			last := (*p.lexer.tokens)[p.lexer.scanpos-1]
+			if last.Pos == perr.Pos && isSync(last.Type) {
+				p.lexer.Back()
+			}
+		}
+		p.skip()
+		ok = true
+	}()
+
+	return f()
+}
+
+// skip skips tokens to the next operator or closing bracket.
+// On "]", it resumes the rest of the element.
+func (p *Parser) skip() {
+	for {
+		t := p.lexer.Peek()
+		if t.Type == ATTREND {
+			p.lexer.Next()
+			p.recovering(func() bool {
+				p.tagElementTail()
+				if p.lexer.Peek().Type == MULT {
+					p.multiplication()
+				}
+				return true
+			})
+			return
+		}
+		if isSync(t.Type) {
+			return
+		}
+		p.lexer.Next()
+	}
+}
+
+func isSync(t TokenType) bool {
+	switch t {
+	case EOF, CHILD, SIBLING, CLIMBUP, GROUPEND, ATTREND:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *Parser) abbreviation() bool {
 	t := p.lexer.Peek()
 	if t.Type == GROUPBEGIN && p.group() {
@@ -76,7 +309,7 @@ func (p *Parser) abbreviation() bool {
 	} else {
 		switch t.Type {
 		case /*tagElement*/ STRING, TEXT:
-			if !p.element() {
+			if !p.include() && !p.macroCall() && !p.element() {
 				return false
 			}
 		default:
@@ -104,24 +337,79 @@ func (p *Parser) abbreviation() bool {
 
 func (p *Parser) element() bool {
 	t := p.lexer.Peek()
-	if t.Type == STRING && p.tagElement() {
-		// nop
-	} else {
-		tok := p.lexer.Next()
-		if tok.Type != TEXT {
-			return false
-		}
-
-		if err := p.builder.Text(tok.Text); err != nil {
-			panic(err)
-		}
+	isTag := false
+	switch {
+	case t.Type == STRING && p.markup():
+	case t.Type == STRING && p.tagElement():
+		isTag = true
+	case !p.text():
+		return false
 	}
 
 	t = p.lexer.Peek()
 	if t.Type == MULT {
 		p.multiplication()
+
+		// li*3{text}
+		if isTag && p.lexer.Peek().Type == TEXT {
+			p.text()
+		}
+	}
+
+	return true
+}
+
+func (p *Parser) text() bool {
+	tok := p.lexer.Next()
+	if tok.Type != TEXT {
+		return false
 	}
 
+	p.at(tok, tok)
+	p.checkPlaceholders(tok)
+	if err := p.builder.Text(tok.Text); err != nil {
+		p.fail(tok, err)
+	}
+	return true
+}
+
+// markup parses a comment (!--{text}), a CDATA section (!cdata{text})
+// or a processing instruction (?target{text}).
+func (p *Parser) markup() bool {
+	first := p.lexer.Peek()
+	target, isPI := strings.CutPrefix(first.Text, "?")
+	if first.Type != STRING || first.Text != "!--" && first.Text != "!cdata" && !isPI {
+		return false
+	}
+	p.lexer.Next()
+
+	mb, ok := p.builder.(MarkupBuilder)
+	if !ok {
+		p.fail(first, errors.New(first.Text+" is not supported"))
+	}
+	if isPI && !isNCName(target) {
+		p.fail(first, errors.New("a target following ? is required"))
+	}
+
+	tok := p.lexer.Next()
+	if tok.Type != TEXT || tok.Pos != tokenEnd(p.lexer.in, first) {
+		p.fail(tok, errors.New("{text} following "+first.Text+" is required"))
+	}
+
+	p.at(first, tok)
+	p.checkPlaceholders(tok)
+	var err error
+	switch {
+	case isPI:
+		err = mb.ProcInst(target, tok.Text)
+	case first.Text == "!--":
+		err = mb.Comment(tok.Text)
+	default:
+		err = mb.CData(tok.Text)
+	}
+	if err != nil {
+		p.fail(tok, err)
+	}
 	return true
 }
 
@@ -131,10 +419,19 @@ func (p *Parser) tagElement() bool {
 		return false
 	}
 
+	p.at(tok, tok)
+	p.checkPlaceholders(tok)
 	if err := p.builder.Element(tok.Text); err != nil {
-		panic(err)
+		p.fail(tok, err)
 	}
 
+	p.tagElementTail()
+
+	return true
+}
+
+// tagElementTail parses { id | class | attr-list }, [ TEXT ].
+func (p *Parser) tagElementTail() {
 	for {
 		t := p.lexer.Peek()
 		switch t.Type {
@@ -156,50 +453,54 @@ func (p *Parser) tagElement() bool {
 		break
 	}
 
-	tok = p.lexer.Next()
+	tok := p.lexer.Next()
 	if tok.Type == TEXT {
+		p.checkPlaceholders(tok)
+		p.at(tok, tok)
 		if err := p.builder.Text(tok.Text); err != nil {
-			panic(err)
+			p.fail(tok, err)
 		}
 
 	} else {
 		p.lexer.Back()
 	}
-
-	return true
 }
 
 func (p *Parser) id() bool {
-	tok := p.lexer.Next()
-	if tok.Type != ID {
+	first := p.lexer.Next()
+	if first.Type != ID {
 		return false
 	}
 
-	tok = p.lexer.Next()
+	tok := p.lexer.Next()
 	if tok.Type != STRING {
-		panic(errors.New("id name is required"))
+		p.fail(tok, errors.New("id name is required"))
 	}
 
+	p.at(first, tok)
+	p.checkPlaceholders(tok)
 	if err := p.builder.ID(tok.Text); err != nil {
-		panic(err)
+		p.fail(tok, err)
 	}
 
 	return true
 }
 
 func (p *Parser) class() bool {
-	tok := p.lexer.Next()
-	if tok.Type != CLASS {
+	first := p.lexer.Next()
+	if first.Type != CLASS {
 		return false
 	}
 
-	tok = p.lexer.Next()
+	tok := p.lexer.Next()
 	if tok.Type != STRING {
-		panic(errors.New("class name is required"))
+		p.fail(tok, errors.New("class name is required"))
 	}
 
+	p.at(first, tok)
+	p.checkPlaceholders(tok)
 	if err := p.builder.Class(tok.Text); err != nil {
-		panic(err)
+		p.fail(tok, err)
 	}
 
 	return true
@@ -211,14 +512,16 @@ func (p *Parser) attr() bool {
 		return false
 	}
 
-	attrname := tok.Text
+	nameTok := tok
 
 	tok = p.lexer.Next()
 	if tok.Type != EQ {
 		p.lexer.Back()
 
-		if err := p.builder.Attribute(attrname, ""); err != nil {
-			panic(err)
+		p.checkPlaceholders(nameTok)
+		p.at(nameTok, p.attrLast(nameTok))
+		if err := p.builder.Attribute(nameTok.Text, ""); err != nil {
+			p.fail(nameTok, err)
 		}
 
 		return true
@@ -226,17 +529,29 @@ func (p *Parser) attr() bool {
 
 	tok = p.lexer.Next()
 	if tok.Type != QTEXT && tok.Type != STRING {
-		panic(errors.New("attr value is required"))
+		p.fail(tok, errors.New("attr value is required"))
 		//return false
 	}
 
-	if err := p.builder.Attribute(attrname, tok.Text); err != nil {
-		panic(err)
+	p.checkPlaceholders(nameTok)
+	p.checkPlaceholders(tok)
+	p.at(nameTok, p.attrLast(tok))
+	if err := p.builder.Attribute(nameTok.Text, tok.Text); err != nil {
+		p.fail(tok, err)
 	}
 
 	return true
 }
 
+// attrLast returns the closing "]" if it follows tok, or tok,
+// so that the range of the last attribute covers the attr-list.
+func (p *Parser) attrLast(tok Token) Token {
+	if t := p.lexer.Peek(); t.Type == ATTREND {
+		return t
+	}
+	return tok
+}
+
 func (p *Parser) attrList() bool {
 	tok := p.lexer.Next()
 	if tok.Type != ATTRBEGIN {
@@ -245,7 +560,7 @@ func (p *Parser) attrList() bool {
 
 	t := p.lexer.Peek()
 	if t.Type != STRING || !p.attr() {
-		panic(errors.New("AttrName as a string is required"))
+		p.fail(t, errors.New("AttrName as a string is required"))
 	}
 
 	for {
@@ -257,7 +572,7 @@ func (p *Parser) attrList() bool {
 
 	tok = p.lexer.Next()
 	if tok.Type != ATTREND {
-		panic(errors.New("] is required in the end of attrs"))
+		p.fail(tok, errors.New("] is required in the end of attrs"))
 		//return false
 	}
 
@@ -267,28 +582,32 @@ func (p *Parser) attrList() bool {
 func (p *Parser) group() bool {
 	tok := p.lexer.Next()
 	if tok.Type == GROUPBEGIN {
+		p.at(tok, tok)
 		if err := p.builder.GroupBegin(); err != nil {
-			panic(err)
+			p.fail(tok, err)
 		}
+		p.depth++
 
 		t := p.lexer.Peek()
 		switch t.Type {
 		case GROUPBEGIN /*tagElement*/, STRING, TEXT:
 			if !p.abbreviation() {
-				panic(errors.New("A group or element is required"))
+				p.fail(p.lexer.Peek(), errors.New("A group or element is required"))
 			}
 		default:
-			panic(errors.New("A group or element is required"))
+			p.fail(t, errors.New("A group or element is required"))
 		}
 
 		tok = p.lexer.Next()
 		if tok.Type != GROUPEND {
-			panic(errors.New(") is required in the end of a group"))
+			p.fail(tok, errors.New(") is required in the end of a group"))
 			//return false
 		}
+		p.at(tok, tok)
 		if err := p.builder.GroupEnd(); err != nil {
-			panic(err)
+			p.fail(tok, err)
 		}
+		p.depth--
 	} else {
 		return false
 	}
@@ -302,40 +621,79 @@ func (p *Parser) group() bool {
 }
 
 func (p *Parser) multiplication() bool {
-	tok := p.lexer.Next()
-	if tok.Type != MULT {
+	first := p.lexer.Next()
+	if first.Type != MULT {
 		return false
 	}
 
-	tok = p.lexer.Next()
+	tok := p.lexer.Next()
 	if tok.Type != STRING {
-		panic(errors.New("a number following * is required"))
+		p.fail(tok, errors.New("a number following * is required"))
 		//return false
 	}
+	if name, found := strings.CutPrefix(tok.Text, "@"); found {
+		db, ok := p.builder.(DataBuilder)
+		if !ok {
+			p.fail(tok, errors.New("multiplication over data is not supported"))
+		}
+		name, tok = p.dataName(name, tok)
+		if name == "" || variableNameLen(name) != len(name) {
+			p.fail(tok, errors.New("a data name following *@ is required"))
+		}
+
+		p.at(first, tok)
+		if err := db.MulData(name); err != nil {
+			p.fail(tok, err)
+		}
+		return true
+	}
 	count, err := strconv.Atoi(tok.Text)
 	if err != nil {
-		panic(errors.New("a number following * is required"))
+		p.fail(tok, errors.New("a number following * is required"))
 		//return false
 	}
 
+	p.at(first, tok)
 	if err := p.builder.Mul(count); err != nil {
-		panic(err)
+		p.fail(tok, err)
 	}
 
 	return true
 }
 
+// dataName continues the name of *@ over "." (@a.b, @.a), which are CLASS tokens.
+// It returns the name and its last token.
+func (p *Parser) dataName(name string, last Token) (string, Token) {
+	for {
+		dot := p.lexer.Peek()
+		if dot.Type != CLASS || dot.Pos != tokenEnd(p.lexer.in, last) {
+			return name, last
+		}
+		p.lexer.Next()
+		name += "."
+		last = dot
+
+		next := p.lexer.Peek()
+		if next.Type != STRING || next.Pos != dot.Pos+1 {
+			return name, last
+		}
+		p.lexer.Next()
+		name += next.Text
+		last = next
+	}
+}
+
 func (p *Parser) operator() bool {
 	tok := p.lexer.Next()
 	if tok.Type == CHILD {
 		if err := p.builder.OpChild(); err != nil {
-			panic(err)
+			p.fail(tok, err)
 		}
 		return true
 
 	} else if tok.Type == SIBLING {
 		if err := p.builder.OpSibling(); err != nil {
-			panic(err)
+			p.fail(tok, err)
 		}
 		return true
 
@@ -351,6 +709,7 @@ func (p *Parser) operator() bool {
 
 func (p *Parser) repeatableOperator() bool {
 	count := 0
+	first := p.lexer.Peek()
 	tok := p.lexer.Next()
 	if tok.Type == CLIMBUP {
 		for {
@@ -365,7 +724,7 @@ func (p *Parser) repeatableOperator() bool {
 
 		if count > 0 {
 			if err := p.builder.OpClimbup(count); err != nil {
-				panic(err)
+				p.fail(first, err)
 			}
 		}
 
@@ -29,6 +29,35 @@ func TestParser(t *testing.T) {
   "hoge"`)
 	})
 
+	t.Run("MulData", func(t *testing.T) {
+		b := []byte(`ul>li*@items{${.name}}>a*@.links`)
+		nl := ennet.NewNodeBuilder(nil)
+
+		err := ennet.Parse(b, &nl)
+		gotwant.TestError(t, err, nil)
+
+		gotwant.Test(t, nl.Root.Dump(), `
+  ul:element
+    li:element *@items
+      "${.name}"
+      a:element *@.links`)
+	})
+
+	t.Run("Markup", func(t *testing.T) {
+		b := []byte(`?xml-stylesheet{href="a.css"}+root>!--{note}*2+script>!cdata{x}`)
+		nl := ennet.NewNodeBuilder(nil)
+
+		err := ennet.Parse(b, &nl)
+		gotwant.TestError(t, err, nil)
+
+		gotwant.Test(t, nl.Root.Dump(), `
+  procinst:"xml-stylesheet href="a.css""
+  root:element
+    comment:"note" *2
+    script:element
+      cdata:"x"`)
+	})
+
 	t.Run("Child", func(t *testing.T) {
 		b := []byte(`a>b>c`)
 		nl := ennet.NewNodeBuilder(nil)
@@ -221,7 +250,7 @@ func TestParserEmmetDocumentation(t *testing.T) {
 		gotwant.Test(t, nl.Root.Dump(), `
   div:element @id=header
   div:element @class=page
-  div:element @class=class1 class2 class3 @id=footer`)
+  div:element @id=footer @class=class1 class2 class3`)
 	})
 
 	t.Run(`td[title="Hello world!" colspan=3]`, func(t *testing.T) {
@@ -232,7 +261,7 @@ func TestParserEmmetDocumentation(t *testing.T) {
 		gotwant.TestError(t, err, nil)
 
 		gotwant.Test(t, nl.Root.Dump(), `
-  td:element @colspan=3 @title=Hello world!`)
+  td:element @title=Hello world! @colspan=3`)
 	})
 
 	t.Run(`ul>li.item$*5`, func(t *testing.T) {
@@ -358,6 +387,25 @@ func TestParserError(t *testing.T) {
 		nl = ennet.NewNodeBuilder(nil)
 		err = ennet.Parse(b, &nl)
 		gotwant.TestError(t, err, "a number following * is required")
+
+		b = []byte(`a*@`)
+		nl = ennet.NewNodeBuilder(nil)
+		err = ennet.Parse(b, &nl)
+		gotwant.TestError(t, err, "a data name following *@ is required")
+
+		b = []byte(`a*@x-y`)
+		nl = ennet.NewNodeBuilder(nil)
+		err = ennet.Parse(b, &nl)
+		gotwant.TestError(t, err, "a data name following *@ is required")
+	})
+
+	t.Run("Markup", func(t *testing.T) {
+		nl := ennet.NewNodeBuilder(nil)
+		gotwant.TestError(t, ennet.Parse([]byte(`!--`), &nl), "{text} following !-- is required")
+		nl = ennet.NewNodeBuilder(nil)
+		gotwant.TestError(t, ennet.Parse([]byte(`!cdata {x}`), &nl), "{text} following !cdata is required")
+		nl = ennet.NewNodeBuilder(nil)
+		gotwant.TestError(t, ennet.Parse([]byte(`?{x}`), &nl), "a target following ? is required")
 	})
 
 	t.Run("OperatorFirst", func(t *testing.T) {
@@ -389,6 +437,104 @@ func TestParserError(t *testing.T) {
 	})
 }
 
+func TestParseTolerant(t *testing.T) {
+	t.Run("NoError", func(t *testing.T) {
+		nl := ennet.NewNodeBuilder(nil)
+		diags := ennet.ParseTolerant([]byte(`a>b+c`), &nl)
+		gotwant.Test(t, len(diags), 0)
+		gotwant.Test(t, nl.Root.Dump(), `
+  a:element
+    b:element
+    c:element`)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		nl := ennet.NewNodeBuilder(nil)
+		diags := ennet.ParseTolerant([]byte(`a#+b.+c[=x]{t}`), &nl)
+		gotwant.Test(t, len(diags), 3)
+		gotwant.TestError(t, diags[0], "id name is required")
+		gotwant.Test(t, diags[0].Pos, 3)
+		gotwant.Test(t, diags[0].End, 4)
+		gotwant.TestError(t, diags[1], "class name is required")
+		gotwant.Test(t, diags[1].Pos, 6)
+		gotwant.TestError(t, diags[2], "AttrName as a string is required")
+		gotwant.Test(t, diags[2].Pos, 9)
+
+		gotwant.Test(t, nl.Root.Dump(), `
+  a:element
+  b:element
+  c:element
+    "t"`)
+	})
+
+	t.Run("Mul", func(t *testing.T) {
+		nl := ennet.NewNodeBuilder(nil)
+		diags := ennet.ParseTolerant([]byte(`ul>li*x>a{$}`), &nl)
+		gotwant.Test(t, len(diags), 1)
+		gotwant.TestError(t, diags[0], "a number following * is required")
+		gotwant.Test(t, diags[0].Pos, 7)
+		gotwant.Test(t, nl.Root.Dump(), `
+  ul:element
+    li:element
+      a:element
+        "$"`)
+	})
+
+	t.Run("Group", func(t *testing.T) {
+		nl := ennet.NewNodeBuilder(nil)
+		diags := ennet.ParseTolerant([]byte(`(a#)*2+b`), &nl)
+		gotwant.Test(t, len(diags), 1)
+		gotwant.TestError(t, diags[0], "id name is required")
+		gotwant.Test(t, nl.Root.Dump(), `
+  :group *2
+    a:element
+  b:element`)
+
+		nl = ennet.NewNodeBuilder(nil)
+		diags = ennet.ParseTolerant([]byte(`(a>b`), &nl)
+		gotwant.Test(t, len(diags), 1)
+		gotwant.TestError(t, diags[0], ") is required")
+		gotwant.Test(t, diags[0].Pos, 5)
+
+		nl = ennet.NewNodeBuilder(nil)
+		diags = ennet.ParseTolerant([]byte(`a)+b`), &nl)
+		gotwant.Test(t, len(diags), 1)
+		gotwant.TestError(t, diags[0], "extra )")
+		gotwant.Test(t, nl.Root.Dump(), `
+  a:element
+  b:element`)
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		nl := ennet.NewNodeBuilder(nil)
+		diags := ennet.ParseTolerant([]byte(`ul>li.item{Item`), &nl)
+		gotwant.Test(t, len(diags), 1)
+		gotwant.TestError(t, diags[0], "sudden EOF")
+		gotwant.Test(t, diags[0].Pos, 16)
+		gotwant.Test(t, nl.Root.FirstChild.FirstChild.GetAttribute("class"), "item")
+
+		nl = ennet.NewNodeBuilder(nil)
+		diags = ennet.ParseTolerant([]byte(`a>`), &nl)
+		gotwant.Test(t, len(diags), 1)
+		gotwant.TestError(t, diags[0], "A group or element is required")
+	})
+}
+
+func TestParseErrorPos(t *testing.T) {
+	nl := ennet.NewNodeBuilder(nil)
+	err := ennet.Parse([]byte(`a>b[x="y"=]`), &nl)
+	perr, ok := err.(*ennet.ParseError)
+	gotwant.Test(t, ok, true)
+	gotwant.Test(t, perr.Pos, 10)
+	gotwant.Test(t, perr.End, 11)
+
+	nl = ennet.NewNodeBuilder(nil)
+	err = ennet.Parse([]byte(`a{x}+"quoted"`), &nl)
+	perr = err.(*ennet.ParseError)
+	gotwant.Test(t, perr.Pos, 6)
+	gotwant.Test(t, perr.End, 14)
+}
+
 func init() {
 	//rog.DisableDebug()
 }
@@ -0,0 +1,73 @@
+package ennet
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrMultipleRoots is reported (in a ParseError) when an abbreviation has more than one root element
+// with WithXMLDeclaration or WithDoctype.
+var ErrMultipleRoots = errors.New("only one root element is allowed with a prolog")
+
+// WithXMLDeclaration writes the XML declaration <?xml version="1.0" encoding="encoding"?>
+// before the root element. The encoding is omitted if encoding is "".
+//
+// With a prolog, the abbreviation must have exactly one root element,
+// which may be surrounded by comments and processing instructions.
+func WithXMLDeclaration(encoding string) Option {
+	return func(o *options) {
+		o.xmlDecl = `<?xml version="1.0"`
+		if encoding != "" {
+			o.xmlDecl += ` encoding="` + encoding + `"`
+		}
+		o.xmlDecl += "?>"
+	}
+}
+
+// WithDoctype writes the document type declaration <!DOCTYPE doctype> before the root element,
+// such as "html" or `svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd"`.
+// It is written after the XML declaration if both are given.
+//
+// With a prolog, the abbreviation must have exactly one root element,
+// which may be surrounded by comments and processing instructions.
+func WithDoctype(doctype string) Option {
+	return func(o *options) {
+		o.doctype = "<!DOCTYPE " + doctype + ">"
+	}
+}
+
+func (o *options) hasProlog() bool {
+	return o.xmlDecl != "" || o.doctype != ""
+}
+
+// writeProlog writes the XML declaration and the document type declaration.
+func (r *renderer) writeProlog() {
+	for _, decl := range [...]string{r.opts.xmlDecl, r.opts.doctype} {
+		if decl == "" {
+			continue
+		}
+		if r.block && r.w.Len() > 0 {
+			r.w.WriteByte('\n')
+		}
+		r.w.WriteString(decl)
+	}
+}
+
+// countRoot counts n if it is a root element, and fails on a second one or a text.
+func (r *renderer) countRoot(n *Node) {
+	switch n.Type {
+	case Element:
+		if _, ok := r.loremCount(n); ok {
+			r.err = &ParseError{Pos: n.Pos, End: n.End, Err: errors.New("text outside the root element")}
+			return
+		}
+		r.roots++
+		if r.roots > 1 {
+			r.err = &ParseError{Pos: n.Pos, End: n.End, Err: ErrMultipleRoots}
+		}
+	case Text:
+		if strings.TrimSpace(n.Data) != "" {
+			r.err = &ParseError{Pos: n.Pos, End: n.End, Err: errors.New("text outside the root element")}
+		}
+	}
+}
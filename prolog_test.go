@@ -0,0 +1,56 @@
+package ennet_test
+
+import (
+	"testing"
+
+	"github.com/shu-go/ennet"
+	"github.com/shu-go/gotwant"
+)
+
+func TestProlog(t *testing.T) {
+	t.Run("XMLDeclaration", func(t *testing.T) {
+		s, err := ennet.Expand(`root>item`, ennet.WithXMLDeclaration("UTF-8"))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<?xml version="1.0" encoding="UTF-8"?><root><item /></root>`)
+
+		s, err = ennet.Expand(`root`, ennet.WithXMLDeclaration(""))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<?xml version="1.0"?><root />`)
+	})
+
+	t.Run("Doctype", func(t *testing.T) {
+		s, err := ennet.Expand(`html>body`, ennet.WithDoctype("html"), ennet.WithMode(ennet.HTML), ennet.WithIndent("  "))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, "<!DOCTYPE html>\n<html>\n  <body></body>\n</html>")
+
+		s, err = ennet.Expand(`svg`, ennet.WithDoctype("svg"), ennet.WithXMLDeclaration("UTF-8"))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<?xml version="1.0" encoding="UTF-8"?><!DOCTYPE svg><svg />`)
+	})
+
+	t.Run("Misc", func(t *testing.T) {
+		s, err := ennet.Expand(`?xml-stylesheet{href="a.css"}+!--{x}+(root)+!--{y}`, ennet.WithXMLDeclaration("UTF-8"))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<?xml version="1.0" encoding="UTF-8"?><?xml-stylesheet href="a.css"?><!--x--><root /><!--y-->`)
+	})
+
+	t.Run("Roots", func(t *testing.T) {
+		_, err := ennet.Expand(`a+b`, ennet.WithDoctype("html"))
+		gotwant.TestError(t, err, ennet.ErrMultipleRoots)
+		perr := err.(*ennet.ParseError)
+		gotwant.Test(t, [2]int{perr.Pos, perr.End}, [2]int{3, 4})
+
+		_, err = ennet.Expand(`li*2`, ennet.WithDoctype("html"))
+		gotwant.TestError(t, err, ennet.ErrMultipleRoots)
+
+		_, err = ennet.Expand(`{x}+a`, ennet.WithDoctype("html"))
+		gotwant.TestError(t, err, "text outside the root element")
+
+		_, err = ennet.Expand(`!--{x}`, ennet.WithDoctype("html"))
+		gotwant.TestError(t, err, "a root element is required")
+
+		s, err := ennet.Expand(`a+b`)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, s, `<a /><b />`)
+	})
+}
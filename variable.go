@@ -0,0 +1,207 @@
+package ennet
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrUndefinedVariable is reported (in a ParseError) when a variable without a fallback
+// is not found in the data.
+var ErrUndefinedVariable = errors.New("undefined variable")
+
+// WithData sets the data of variables.
+//
+// A variable ${name} is replaced by the value of name in data,
+// and ${name:fallback} by fallback if name is not found or nil.
+// A name is a path separated by ".", such as ${user.name}.
+// In a multiplication over data (li*@items), ${.name} refers to the current item,
+// ${.} is the item itself, and $ is its number as usual.
+// Other names are looked up in the items from the innermost, then in data.
+// data is a map with string keys or a struct (exported fields), or a pointer to them.
+//
+// Values are written as data: they are not parsed as an abbreviation,
+// "$" in them is not a numbering, and &, <, >, " are always escaped.
+// Element and attribute names with variables must not contain spaces, quotes, <, >, /, = or &.
+func WithData(data any) Option {
+	return func(o *options) {
+		o.data = data
+	}
+}
+
+// variableLen returns the length of the variable (${name} or ${name:fallback}) at the start of s,
+// or 0 if s does not start with a variable.
+func variableLen(s string) int {
+	if !strings.HasPrefix(s, "${") {
+		return 0
+	}
+
+	i := 2 + variableNameLen(s[2:])
+	if i == 2 {
+		return 0
+	}
+
+	if i < len(s) && s[i] == ':' {
+		end := strings.IndexByte(s[i:], '}')
+		if end == -1 {
+			return 0
+		}
+		return i + end + 1
+	}
+	if i < len(s) && s[i] == '}' {
+		return i + 1
+	}
+	return 0
+}
+
+// variableNameLen returns the length of the name (a.b, .a.b or .) at the start of s.
+func variableNameLen(s string) int {
+	i := 0
+	if i < len(s) && s[i] == '.' {
+		i++ // .name or .
+	} else if !isVariableNameStart(s, i) {
+		return 0
+	}
+	for isVariableNameStart(s, i) {
+		for i < len(s) && isVariableNameByte(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '.' && isVariableNameStart(s, i+1) {
+			i++
+			continue
+		}
+		break
+	}
+	return i
+}
+
+func isVariableNameStart(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isVariableNameByte(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// parseVariable splits a variable v (${name:fallback}) into its parts.
+func parseVariable(v string) (name, fallback string, hasFallback bool) {
+	v = v[2 : len(v)-1]
+	name, fallback, hasFallback = strings.Cut(v, ":")
+	return name, fallback, hasFallback
+}
+
+// lookup returns the value of the variable name as a string.
+func (r *renderer) lookup(name string) (string, bool) {
+	v, ok := r.lookupValue(name)
+	if !ok {
+		return "", false
+	}
+	if s, ok := v.Interface().(string); ok {
+		return s, true
+	}
+	return fmt.Sprint(v.Interface()), true
+}
+
+// lookupValue returns the value of the variable name.
+//
+// A name starting with "." is looked up in the current item of *@,
+// and other names in the items from the innermost, then in the data.
+func (r *renderer) lookupValue(name string) (reflect.Value, bool) {
+	if strings.HasPrefix(name, ".") {
+		if len(r.items) > 0 {
+			return lookupPath(r.items[len(r.items)-1], name)
+		}
+		return lookupPath(reflect.ValueOf(r.opts.data), name)
+	}
+
+	for i := len(r.items) - 1; i >= 0; i-- {
+		if v, ok := lookupPath(r.items[i], name); ok {
+			return v, true
+		}
+	}
+	return lookupPath(reflect.ValueOf(r.opts.data), name)
+}
+
+// expandData expands n once per item of the data n.MulData.
+func (r *renderer) expandData(n *Node) {
+	v, found := r.lookupValue(n.MulData)
+	if !found {
+		r.err = &ParseError{Pos: n.Pos, End: n.End, Err: fmt.Errorf("%w %s", ErrUndefinedVariable, n.MulData)}
+		return
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		r.err = &ParseError{Pos: n.Pos, End: n.End, Err: fmt.Errorf("%s is not a slice", n.MulData)}
+		return
+	}
+
+	count := v.Len()
+	if !r.checkMul(n, count, ErrMulLimit) {
+		return
+	}
+
+	for i := range count {
+		if r.err != nil {
+			return
+		}
+		r.iters = append(r.iters, iteration{index: i, count: count})
+		r.items = append(r.items, v.Index(i))
+		r.breakLine(n)
+		r.expandMapped(n)
+		r.items = r.items[:len(r.items)-1]
+		r.iters = r.iters[:len(r.iters)-1]
+	}
+}
+
+// lookupPath follows path (a.b.c) from v.
+// A leading "." refers to v itself.
+func lookupPath(v reflect.Value, path string) (reflect.Value, bool) {
+	path = strings.TrimPrefix(path, ".")
+	for {
+		v = indirect(v)
+		if !v.IsValid() {
+			return reflect.Value{}, false
+		}
+		if path == "" {
+			return v, true
+		}
+
+		key, rest, _ := strings.Cut(path, ".")
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, false
+			}
+			v = v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		case reflect.Struct:
+			f, found := v.Type().FieldByName(key)
+			if !found || !f.IsExported() {
+				return reflect.Value{}, false
+			}
+			fv, err := v.FieldByIndexErr(f.Index)
+			if err != nil {
+				return reflect.Value{}, false // nil embedded pointer
+			}
+			v = fv
+		default:
+			return reflect.Value{}, false
+		}
+		path = rest
+	}
+}
+
+// indirect dereferences pointers and interfaces.
+// It returns the zero Value for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}